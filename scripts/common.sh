@@ -84,7 +84,7 @@ _go_vet() {
 }
 
 _go_race() {
-  go test -race ./...
+  go test -race --tags="${TEST_TYPE}" ./...
 }
 
 _go_test() {
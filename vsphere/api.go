@@ -21,69 +21,216 @@ package vsphere
 
 import (
 	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/event"
 	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/methods"
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
 type govmomiAPI struct {
 	// Govmomi API objects
-	client  *govmomi.Client
-	finder  *find.Finder
-	pc      *property.Collector
-	cluster *mo.ClusterComputeResource
+	client *govmomi.Client
+	finder *find.Finder
+	pc     *property.Collector
+
+	// Datacenters matching configured datacenter names
+	datacenters []*object.Datacenter
+
+	// Clusters matching configured cluster names in all configured datacenters
+	configuredClusters []mo.ClusterComputeResource
+
+	// Compute resources of standalone (non-clustered) hosts matching configured host names
+	standaloneResources []mo.ComputeResource
+
+	// Names of datacenters configured compute resources, their hosts and datastores belong to, mapped by entity reference
+	datacenterNames map[string]string
+
+	// SSO token used to log in, nil if session is authenticated with password or certificate
+	token *ssoToken
+
+	// Closed to stop requests keeping session alive
+	keepAliveStop chan struct{}
+
+	// Optional properties retrieved for hosts and VMs, mapped by entity type ("host" or "vm")
+	optionalProperties map[string][]string
 
 	// Cache variables
-	datastores []mo.Datastore
-	hosts      []mo.HostSystem
-	vms        map[string][]mo.VirtualMachine
-	metrics    []types.PerfCounterInfo
+	clusters      []mo.ClusterComputeResource
+	datastores    []mo.Datastore
+	hosts         []mo.HostSystem
+	vms           map[string][]mo.VirtualMachine
+	resourcePools []mo.ResourcePool
+	metrics       []types.PerfCounterInfo
+	intervals     []types.PerfInterval
+	alarms        map[string]mo.Alarm
+	events        []types.BaseEvent
+
+	// Time range of cached events
+	eventsSince time.Time
+	eventsUntil time.Time
 }
 
 // Init initializes all necessary objects to send API calls to vSphere
 // TODO: Mock Init's inside functions instead of making 2 versions of Init()
-func (a *govmomiAPI) Init(ctx context.Context, url, username, password, clusterName string, datacenterName string, insecure bool) error {
+func (a *govmomiAPI) Init(ctx context.Context, cfg connectionConfig) error {
 	var err error
 	if a.client == nil {
-		a.client, err = initializeClient(ctx, url, username, password, insecure)
+		a.client, a.token, err = initializeClient(ctx, cfg)
 		if err != nil {
 			return fmt.Errorf("unable to initialize vSphere client: %v", err)
 		}
+		a.startKeepAlive()
 	}
 
 	if a.finder == nil {
-		a.finder, err = initializeFinder(ctx, a.client, datacenterName)
-		if err != nil {
-			return fmt.Errorf("unable to initialize vSphere finder: %v", err)
-		}
+		a.finder = find.NewFinder(a.client.Client, true)
 	}
 
 	if a.pc == nil {
 		a.pc = property.DefaultCollector(a.client.Client)
 	}
 
-	if a.cluster == nil {
-		a.cluster, err = findCluster(ctx, a.finder, a.pc, clusterName)
+	if a.datacenters == nil {
+		a.datacenters, err = findDatacenters(ctx, a.finder, splitNames(cfg.datacenterName))
+		if err != nil {
+			return fmt.Errorf("unable to find datacenter: %v", err)
+		}
+	}
+
+	if a.datacenterNames == nil {
+		clusterNames, hostNames := computeResourceNames(cfg, a.client.ServiceContent.About.ApiType)
+		a.configuredClusters, a.standaloneResources, a.datacenterNames, err = findComputeResources(ctx, a.finder, a.pc, a.datacenters, clusterNames, hostNames)
 		if err != nil {
-			return fmt.Errorf("unable to find cluster: %v", err)
+			return fmt.Errorf("unable to find compute resources: %v", err)
 		}
 	}
 
 	return nil
 }
 
+// Close logs out of vSphere session
+func (a *govmomiAPI) Close(ctx context.Context) error {
+	a.stopKeepAlive()
+	if a.client == nil {
+		return nil
+	}
+	return a.client.Logout(ctx)
+}
+
+// startKeepAlive periodically sends request to vCenter in background, so session does not time out
+// when collection interval is longer than vCenter session timeout or pooled session is not used for a while
+func (a *govmomiAPI) startKeepAlive() {
+	stop := make(chan struct{})
+	a.keepAliveStop = stop
+	client := a.client.Client
+
+	go func() {
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				// Response is not needed, lost session is detected and renewed before next collection
+				methods.GetCurrentTime(context.Background(), client)
+			}
+		}
+	}()
+}
+
+// stopKeepAlive stops requests keeping session alive, if they were started
+func (a *govmomiAPI) stopKeepAlive() {
+	if a.keepAliveStop != nil {
+		close(a.keepAliveStop)
+		a.keepAliveStop = nil
+	}
+}
+
+// SessionActive checks whether vSphere session is still authenticated
+// Session logged in with SSO token is reported as inactive shortly before token expiry, so it is renewed with new token
+func (a *govmomiAPI) SessionActive(ctx context.Context) (bool, error) {
+	if a.client == nil {
+		return false, nil
+	}
+	if a.token != nil && time.Now().Add(tokenRenewMargin).After(a.token.expires) {
+		return false, nil
+	}
+
+	// UserSession returns nil session on NotAuthenticated fault
+	session, err := a.client.SessionManager.UserSession(ctx)
+	if err != nil {
+		return false, err
+	}
+	return session != nil, nil
+}
+
+// Reset logs out of vSphere session and drops client and all objects built on it, so next Init logs in again
+// and rebuilds finder, property collector, datacenters and compute resources
+// Session is logged out even if it is still active (i.e. SSO token is about to expire), so sessions are not leaked on vCenter
+func (a *govmomiAPI) Reset(ctx context.Context) {
+	logoutCtx, cancel := context.WithTimeout(ctx, logoutTimeout)
+	defer cancel()
+	// Session might be already expired on vCenter side, so logout error is irrelevant
+	a.Close(logoutCtx)
+
+	a.client = nil
+	a.token = nil
+	a.finder = nil
+	a.pc = nil
+	a.datacenters = nil
+	a.configuredClusters = nil
+	a.standaloneResources = nil
+	a.datacenterNames = nil
+	a.ClearCache()
+}
+
 // Clear API retrieve cache
 func (a *govmomiAPI) ClearCache() {
+	a.clusters = nil
 	a.datastores = nil
 	a.hosts = nil
 	a.vms = nil
+	a.resourcePools = nil
 	a.metrics = nil
+	a.intervals = nil
+	a.alarms = nil
+	a.events = nil
+}
+
+// SetOptionalProperties sets optional properties retrieved for hosts and VMs in addition to always retrieved ones,
+// cached hosts and VMs are cleared, so they are retrieved again with new properties
+func (a *govmomiAPI) SetOptionalProperties(props map[string][]string) {
+	a.optionalProperties = props
+	a.hosts = nil
+	a.vms = nil
+}
+
+// computeResources returns configured clusters and standalone hosts as generic compute resources
+func (a *govmomiAPI) computeResources() []mo.ComputeResource {
+	crs := []mo.ComputeResource{}
+	for _, cluster := range a.configuredClusters {
+		crs = append(crs, cluster.ComputeResource)
+	}
+	return append(crs, a.standaloneResources...)
 }
 
 // RetrieveCounters retrieves vSphere cluster metric list that are available for user
@@ -101,14 +248,58 @@ func (a *govmomiAPI) RetrieveCounters(ctx context.Context) ([]types.PerfCounterI
 	return a.metrics, nil
 }
 
-// RetrieveDatastores retrieves all datastores for cluster
-// NOTE: For future development, for now datastore metrics are not available due to API limitations
+// RetrievePerfIntervals retrieves historical intervals configured on vCenter
+func (a *govmomiAPI) RetrievePerfIntervals(ctx context.Context) ([]types.PerfInterval, error) {
+	if a.intervals == nil {
+		var perfManager mo.PerformanceManager
+
+		err := a.client.RetrieveOne(ctx, *a.client.ServiceContent.PerfManager, []string{"historicalInterval"}, &perfManager)
+		if err != nil {
+			return nil, err
+		}
+
+		a.intervals = perfManager.HistoricalInterval
+	}
+	return a.intervals, nil
+}
+
+// RetrieveClusters retrieves configured clusters with their current summary
+func (a *govmomiAPI) RetrieveClusters(ctx context.Context) ([]mo.ClusterComputeResource, error) {
+	if a.clusters == nil && len(a.configuredClusters) != 0 {
+		refs := []types.ManagedObjectReference{}
+		for _, cluster := range a.configuredClusters {
+			refs = append(refs, cluster.Reference())
+		}
+		err := a.pc.Retrieve(ctx, refs, []string{"name", "summary", "triggeredAlarmState"}, &a.clusters)
+		if err != nil {
+			return nil, wrapError("unable to retrieve clusters", err)
+		}
+	}
+
+	return a.clusters, nil
+}
+
+// RetrieveDatacenterName retrieves name of datacenter which configured compute resource, host or datastore with given reference belongs to
+func (a *govmomiAPI) RetrieveDatacenterName(ctx context.Context, ref types.ManagedObjectReference) (string, error) {
+	dcName, ok := a.datacenterNames[ref.Value]
+	if !ok {
+		return "", fmt.Errorf("cannot find datacenter of %s", ref.Value)
+	}
+	return dcName, nil
+}
+
+// RetrieveDatastores retrieves all datastores for configured clusters and standalone hosts
+// Datastores shared between compute resources are retrieved only once
 func (a *govmomiAPI) RetrieveDatastores(ctx context.Context) ([]mo.Datastore, error) {
 	if a.datastores == nil {
-		if len(a.cluster.Datastore) != 0 {
-			err := a.pc.Retrieve(ctx, a.cluster.Datastore, nil, &a.datastores)
+		refs := []types.ManagedObjectReference{}
+		for _, cr := range a.computeResources() {
+			refs = appendUniqueRefs(refs, cr.Datastore)
+		}
+		if len(refs) != 0 {
+			err := a.pc.Retrieve(ctx, refs, []string{"name", "summary", "info", "host", "triggeredAlarmState"}, &a.datastores)
 			if err != nil {
-				return nil, fmt.Errorf("unable to retrieve datastores: %v", err)
+				return nil, wrapError("unable to retrieve datastores", err)
 			}
 		}
 	}
@@ -116,13 +307,18 @@ func (a *govmomiAPI) RetrieveDatastores(ctx context.Context) ([]mo.Datastore, er
 	return a.datastores, nil
 }
 
-// RetrieveHosts finds all hosts on given vSphere cluster
+// RetrieveHosts finds all hosts on configured vSphere clusters and configured standalone hosts
 func (a *govmomiAPI) RetrieveHosts(ctx context.Context) ([]mo.HostSystem, error) {
 	if a.hosts == nil {
-		if len(a.cluster.Host) != 0 {
-			err := a.pc.Retrieve(ctx, a.cluster.Host, []string{"name", "vm", "systemResources", "hardware"}, &a.hosts)
+		refs := []types.ManagedObjectReference{}
+		for _, cr := range a.computeResources() {
+			refs = appendUniqueRefs(refs, cr.Host)
+		}
+		if len(refs) != 0 {
+			props := append([]string{"name", "parent", "vm", "systemResources", "summary", "triggeredAlarmState"}, a.optionalProperties["host"]...)
+			err := a.pc.Retrieve(ctx, refs, props, &a.hosts)
 			if err != nil {
-				return nil, fmt.Errorf("unable to retrieve hosts: %v", err)
+				return nil, wrapError("unable to retrieve hosts", err)
 			}
 		}
 	}
@@ -138,10 +334,11 @@ func (a *govmomiAPI) RetrieveVMs(ctx context.Context, host mo.HostSystem) ([]mo.
 	if a.vms[host.Reference().Value] == nil {
 		if len(host.Vm) != 0 {
 			vmsData := []mo.VirtualMachine{}
-			err := a.pc.Retrieve(ctx, host.Vm, []string{"name", "summary"}, &vmsData)
+			props := append([]string{"name", "summary", "resourcePool", "triggeredAlarmState"}, a.optionalProperties["vm"]...)
+			err := a.pc.Retrieve(ctx, host.Vm, props, &vmsData)
 			a.vms[host.Reference().Value] = vmsData
 			if err != nil {
-				return nil, fmt.Errorf("unable to retrieve virtual machines: %v", err)
+				return nil, wrapError("unable to retrieve virtual machines", err)
 			}
 		}
 	}
@@ -149,6 +346,118 @@ func (a *govmomiAPI) RetrieveVMs(ctx context.Context, host mo.HostSystem) ([]mo.
 	return a.vms[host.Reference().Value], nil
 }
 
+// RetrieveResourcePools retrieves all resource pools (including root resource pools and vApps) for configured clusters and standalone hosts
+func (a *govmomiAPI) RetrieveResourcePools(ctx context.Context) ([]mo.ResourcePool, error) {
+	if a.resourcePools == nil {
+		pools := []mo.ResourcePool{}
+
+		// Walk resource pool trees level by level, starting from compute resource root resource pools
+		refs := []types.ManagedObjectReference{}
+		for _, cr := range a.computeResources() {
+			if cr.ResourcePool != nil {
+				refs = append(refs, *cr.ResourcePool)
+			}
+		}
+		for len(refs) != 0 {
+			level := []mo.ResourcePool{}
+			err := a.pc.Retrieve(ctx, refs, []string{"name", "owner", "summary", "vm", "resourcePool"}, &level)
+			if err != nil {
+				return nil, wrapError("unable to retrieve resource pools", err)
+			}
+
+			refs = nil
+			for _, pool := range level {
+				refs = append(refs, pool.ResourcePool...)
+			}
+			pools = append(pools, level...)
+		}
+		a.resourcePools = pools
+	}
+
+	return a.resourcePools, nil
+}
+
+// RetrieveAlarms retrieves definitions of alarms with given references
+func (a *govmomiAPI) RetrieveAlarms(ctx context.Context, refs []types.ManagedObjectReference) ([]mo.Alarm, error) {
+	if a.alarms == nil {
+		a.alarms = make(map[string]mo.Alarm)
+	}
+
+	// Retrieve only alarms which are not cached yet
+	missing := []types.ManagedObjectReference{}
+	for _, ref := range refs {
+		if _, ok := a.alarms[ref.Value]; !ok {
+			missing = append(missing, ref)
+		}
+	}
+	if len(missing) != 0 {
+		alarms := []mo.Alarm{}
+		err := a.pc.Retrieve(ctx, missing, []string{"info"}, &alarms)
+		if err != nil {
+			return nil, wrapError("unable to retrieve alarms", err)
+		}
+		for _, alarm := range alarms {
+			a.alarms[alarm.Reference().Value] = alarm
+		}
+	}
+
+	result := []mo.Alarm{}
+	for _, ref := range refs {
+		if alarm, ok := a.alarms[ref.Value]; ok {
+			result = append(result, alarm)
+		}
+	}
+	return result, nil
+}
+
+// RetrieveEvents retrieves events created on vCenter since given time, together with vCenter time events were read up to
+// If given time is zero, no events are returned, so the returned time can be used as a starting point for following calls
+func (a *govmomiAPI) RetrieveEvents(ctx context.Context, since time.Time) ([]types.BaseEvent, time.Time, error) {
+	if a.events != nil && a.eventsSince.Equal(since) {
+		return a.events, a.eventsUntil, nil
+	}
+
+	// vCenter time is used, so events are not lost or repeated when local clock differs
+	now, err := methods.GetCurrentTime(ctx, a.client)
+	if err != nil {
+		return nil, time.Time{}, wrapError("unable to retrieve vCenter time", err)
+	}
+
+	events := []types.BaseEvent{}
+	if !since.IsZero() {
+		filter := types.EventFilterSpec{
+			Time: &types.EventFilterSpecByTime{BeginTime: &since, EndTime: now},
+		}
+		collector, err := event.NewManager(a.client.Client).CreateCollectorForEvents(ctx, filter)
+		if err != nil {
+			return nil, time.Time{}, wrapError("unable to create event collector", err)
+		}
+		// Collector is created for each read instead of being kept for the session: vCenter limits number of
+		// history collectors per session, each event cursor would need its own collector, and collectors are lost
+		// whenever session is reset or reaped. Time range filter gives the same events with no server-side state.
+		defer collector.Destroy(ctx)
+
+		// Read all pages of events from given time range
+		for {
+			page, err := collector.ReadNextEvents(ctx, eventPageSize)
+			if err != nil {
+				return nil, time.Time{}, wrapError("unable to read events", err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			events = append(events, page...)
+		}
+	}
+
+	// Both ends of time range are inclusive, so next range starts right after this one
+	a.events = events
+	a.eventsSince = since
+	a.eventsUntil = now.Add(time.Nanosecond)
+
+	return a.events, a.eventsUntil, nil
+}
+
 // PerfQuery builds query object containing given query specs and sends it through govmomi API
 // Response from PerfQuery() is built in following way:
 // response.Returnval is a slice with results for all given entities (hosts, VMs, disks). Each element of this slice contains a slice with results for all given instances (CPU cores, VM NIC, etc.). Each element from instances slice contains a slice with integer values for given period. In our case, there's only one value in the last slice (we are retrieveing real-time data).
@@ -160,58 +469,297 @@ func (a *govmomiAPI) PerfQuery(ctx context.Context, querySpecs []types.PerfQuery
 	return methods.QueryPerf(ctx, a.client.RoundTripper, &query)
 }
 
+// QueryAvailableCounters retrieves keys of perf counters available at real-time interval for given entity
+func (a *govmomiAPI) QueryAvailableCounters(ctx context.Context, entity types.ManagedObjectReference) ([]int32, error) {
+	query := types.QueryAvailablePerfMetric{
+		This:       *a.client.ServiceContent.PerfManager,
+		Entity:     entity,
+		IntervalId: defaultIntervalID,
+	}
+	res, err := methods.QueryAvailablePerfMetric(ctx, a.client.RoundTripper, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Counter is listed once for each of its instances
+	seen := map[int32]bool{}
+	keys := []int32{}
+	for _, id := range res.Returnval {
+		if !seen[id.CounterId] {
+			seen[id.CounterId] = true
+			keys = append(keys, id.CounterId)
+		}
+	}
+	return keys, nil
+}
+
 // initializeClient initializes vSphere API client
-func initializeClient(ctx context.Context, hosturl, username, password string, insecure bool) (*govmomi.Client, error) {
+// SSO token used to log in is returned if token authentication is configured
+func initializeClient(ctx context.Context, cfg connectionConfig) (*govmomi.Client, *ssoToken, error) {
 
 	// TODO: Check whether user added "https://" prefix and "/sdk" suffix in URL
-	vURL, err := url.Parse(hosturl)
+	vURL, err := url.Parse(cfg.url)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	soapClient, err := newSoapClient(vURL, cfg)
+	if err != nil {
+		return nil, nil, err
 	}
-	vURL.User = url.UserPassword(username, password)
 
 	// Initialize client
-	c, err := govmomi.NewClient(ctx, vURL, insecure)
+	vimClient, err := vim25.NewClient(ctx, soapClient)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	c := &govmomi.Client{
+		Client:         vimClient,
+		SessionManager: session.NewManager(vimClient),
 	}
 
-	return c, nil
+	var token *ssoToken
+	switch {
+	case cfg.extensionKey != "":
+		err = c.LoginExtensionByCertificate(ctx, cfg.extensionKey, "")
+	case cfg.authMode == authModeToken:
+		// With client certificate holder-of-key token bound to it is requested, otherwise bearer token
+		var signer *tokenSigner
+		if cfg.certFile != "" {
+			signer, err = newTokenSigner(cfg.certFile, cfg.keyFile)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		// Token is issued through the same transport, so STS endpoint is verified with the same TLS settings
+		token, err = issueToken(ctx, &soapClient.Client, stsURL(vURL, cfg.ssoURL), cfg.username, cfg.password, signer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to issue SSO token: %v", err)
+		}
+		err = loginByToken(ctx, soapClient, *c.ServiceContent.SessionManager, token)
+	default:
+		err = c.Login(ctx, url.UserPassword(cfg.username, cfg.password))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c, token, nil
 }
 
-// initializeFinder initializes and prepares vSphere API finder
-func initializeFinder(ctx context.Context, client *govmomi.Client, datacenterName string) (*find.Finder, error) {
-	f := find.NewFinder(client.Client, true)
-	dc, err := f.DatacenterOrDefault(ctx, datacenterName)
-	if err != nil {
-		return nil, fmt.Errorf("unable to find default datacenter: %v", err)
+// newSoapClient creates SOAP client for given endpoint with TLS settings from given config
+// Without extensionKey client certificate is only presented in TLS handshake (mutual TLS), and session is still
+// logged in with username and password
+func newSoapClient(vURL *url.URL, cfg connectionConfig) (*soap.Client, error) {
+	soapClient := soap.NewClient(vURL, cfg.insecure)
+	tlsConfig := soapClient.Client.Transport.(*http.Transport).TLSClientConfig
+
+	if cfg.caFile != "" {
+		if err := soapClient.SetRootCAs(cfg.caFile); err != nil {
+			return nil, fmt.Errorf("unable to load CA bundle: %v", err)
+		}
 	}
-	f.SetDatacenter(dc)
 
-	return f, nil
+	if cfg.thumbprint != "" {
+		verify, err := thumbprintVerifier(cfg.thumbprint)
+		if err != nil {
+			return nil, err
+		}
+		// Pinned certificate replaces chain verification, so self-signed ESXi certificates can be trusted
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verify
+	}
+
+	if cfg.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.certFile, cfg.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %v", err)
+		}
+		if cfg.extensionKey != "" {
+			// Extension login requires certificate to be sent through vCenter sdk tunnel
+			soapClient.SetCertificate(cert)
+		} else {
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	return soapClient, nil
 }
 
-// findCluster finds cluster (mo.ClusterComputeResource type) with specified name (clusterName),
+// thumbprintVerifier returns TLS peer certificate verification function accepting only server certificate
+// with given SHA-1 or SHA-256 thumbprint (hex digest, colons and case are ignored)
+func thumbprintVerifier(thumbprint string) (func([][]byte, [][]*x509.Certificate) error, error) {
+	pin := strings.ToLower(strings.Replace(thumbprint, ":", "", -1))
+
+	var sum func([]byte) []byte
+	switch len(pin) {
+	case sha1.Size * 2:
+		sum = func(b []byte) []byte {
+			s := sha1.Sum(b)
+			return s[:]
+		}
+	case sha256.Size * 2:
+		sum = func(b []byte) []byte {
+			s := sha256.Sum256(b)
+			return s[:]
+		}
+	default:
+		return nil, fmt.Errorf("invalid thumbprint %s, expected SHA-1 or SHA-256 digest", thumbprint)
+	}
+
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("server did not present a certificate")
+		}
+		if peer := hex.EncodeToString(sum(rawCerts[0])); peer != pin {
+			return fmt.Errorf("server certificate thumbprint %s does not match configured thumbprint", peer)
+		}
+		return nil
+	}, nil
+}
+
+// findDatacenters finds datacenters matching any of specified names or wildcard patterns (datacenterNames)
+// If no names are specified, all datacenters are returned
+func findDatacenters(ctx context.Context, f *find.Finder, datacenterNames []string) ([]*object.Datacenter, error) {
+	if len(datacenterNames) == 0 {
+		datacenterNames = []string{"*"}
+	}
+
+	datacenters := []*object.Datacenter{}
+	for _, datacenterName := range datacenterNames {
+		dcList, err := f.DatacenterList(ctx, datacenterName)
+		if err != nil {
+			// Pattern matching no datacenter is not an error as long as other patterns match
+			if _, ok := err.(*find.NotFoundError); ok {
+				continue
+			}
+			return nil, fmt.Errorf("unable to find datacenter list: %v", err)
+		}
+		for _, dc := range dcList {
+			duplicate := false
+			for _, d := range datacenters {
+				if d.Reference().Value == dc.Reference().Value {
+					duplicate = true
+				}
+			}
+			if !duplicate {
+				datacenters = append(datacenters, dc)
+			}
+		}
+	}
+	if len(datacenters) == 0 {
+		return nil, fmt.Errorf("datacenter list is empty")
+	}
+
+	return datacenters, nil
+}
+
+// findComputeResources finds clusters matching any of specified names or wildcard patterns (clusterNames)
+// and compute resources of standalone hosts matching any of specified names or patterns (hostNames) in all given datacenters,
 // using previously initialized property collector and finder
-func findCluster(ctx context.Context, f *find.Finder, pc *property.Collector, clusterName string) (*mo.ClusterComputeResource, error) {
+// Names of datacenters found compute resources, their hosts and datastores belong to are returned in map indexed by entity reference
+func findComputeResources(ctx context.Context, f *find.Finder, pc *property.Collector, datacenters []*object.Datacenter, clusterNames []string, hostNames []string) ([]mo.ClusterComputeResource, []mo.ComputeResource, map[string]string, error) {
+	clusterRefs := []types.ManagedObjectReference{}
+	standaloneRefs := []types.ManagedObjectReference{}
+	datacenterNames := map[string]string{}
+	for _, dc := range datacenters {
+		f.SetDatacenter(dc)
+		for _, clusterName := range clusterNames {
+			clusterList, err := f.ClusterComputeResourceList(ctx, clusterName)
+			if err != nil {
+				// Pattern matching no cluster is not an error as long as other patterns match
+				if _, ok := err.(*find.NotFoundError); ok {
+					continue
+				}
+				return nil, nil, nil, fmt.Errorf("unable to find cluster compute resource list: %v", err)
+			}
+			for _, cluster := range clusterList {
+				clusterRefs = appendUniqueRefs(clusterRefs, []types.ManagedObjectReference{cluster.Reference()})
+				datacenterNames[cluster.Reference().Value] = dc.Name()
+			}
+		}
+		for _, hostName := range hostNames {
+			crList, err := f.ComputeResourceList(ctx, hostName)
+			if err != nil {
+				if _, ok := err.(*find.NotFoundError); ok {
+					continue
+				}
+				return nil, nil, nil, fmt.Errorf("unable to find compute resource list: %v", err)
+			}
+			for _, cr := range crList {
+				// Compute resource list contains clusters as well, only standalone hosts are taken
+				if cr.Reference().Type != "ComputeResource" {
+					continue
+				}
+				standaloneRefs = appendUniqueRefs(standaloneRefs, []types.ManagedObjectReference{cr.Reference()})
+				datacenterNames[cr.Reference().Value] = dc.Name()
+			}
+		}
+	}
+	if len(clusterRefs) == 0 && len(standaloneRefs) == 0 {
+		return nil, nil, nil, fmt.Errorf("no clusters or standalone hosts found")
+	}
 
-	clusterList, err := f.ClusterComputeResourceList(ctx, clusterName)
-	if err != nil {
-		return nil, fmt.Errorf("unable to find cluster compute resource list: %v", err)
+	clusters := []mo.ClusterComputeResource{}
+	if len(clusterRefs) != 0 {
+		err := pc.Retrieve(ctx, clusterRefs, []string{"name", "host", "datastore", "resourcePool"}, &clusters)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("unable to retrieve clusters from references: %v", err)
+		}
 	}
-	if len(clusterList) == 0 {
-		return nil, fmt.Errorf("cluster compute resource list is empty")
+	standalone := []mo.ComputeResource{}
+	if len(standaloneRefs) != 0 {
+		err := pc.Retrieve(ctx, standaloneRefs, []string{"name", "host", "datastore", "resourcePool"}, &standalone)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("unable to retrieve standalone hosts from references: %v", err)
+		}
 	}
 
-	// There's only one cluster in cluster list if found
-	clusterRef := clusterList[0].Reference()
+	// Hosts and datastores belong to the same datacenter as their compute resource
+	crs := []mo.ComputeResource{}
+	for _, cluster := range clusters {
+		crs = append(crs, cluster.ComputeResource)
+	}
+	for _, cr := range append(crs, standalone...) {
+		for _, ref := range cr.Host {
+			datacenterNames[ref.Value] = datacenterNames[cr.Reference().Value]
+		}
+		for _, ref := range cr.Datastore {
+			datacenterNames[ref.Value] = datacenterNames[cr.Reference().Value]
+		}
+	}
 
-	cluster := mo.ClusterComputeResource{}
-	err = pc.Retrieve(ctx, []types.ManagedObjectReference{clusterRef}, []string{"name", "host", "datastore"}, &cluster)
-	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve cluster from reference: %v", err)
+	return clusters, standalone, datacenterNames, nil
+}
+
+// computeResourceNames returns name patterns of clusters and standalone hosts to collect for given configuration
+// Without clusterName and standaloneHostName all clusters and all standalone hosts are collected
+func computeResourceNames(cfg connectionConfig, apiType string) ([]string, []string) {
+	// ESXi host connected directly (without vCenter) cannot be clustered, so its own compute resource is collected
+	if apiType == "HostAgent" {
+		return nil, []string{"*"}
 	}
 
-	return &cluster, nil
+	clusterNames := splitNames(cfg.clusterName)
+	hostNames := splitNames(cfg.standaloneHostName)
+	if len(clusterNames) == 0 && len(hostNames) == 0 {
+		return []string{"*"}, []string{"*"}
+	}
+	return clusterNames, hostNames
+}
 
+// appendUniqueRefs appends references to given slice, skipping references which are already present
+func appendUniqueRefs(refs []types.ManagedObjectReference, newRefs []types.ManagedObjectReference) []types.ManagedObjectReference {
+	for _, ref := range newRefs {
+		duplicate := false
+		for _, r := range refs {
+			if r.Value == ref.Value {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
 }
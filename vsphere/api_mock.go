@@ -22,8 +22,10 @@ package vsphere
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -32,25 +34,71 @@ type counterInfo struct {
 	name   string
 	group  string
 	rollup string
+	level  int32
+
+	// Type of the only entity counter is available for, counters without entity are available for hosts and VMs
+	entity string
 }
 
 type counterData struct {
 	key      int32
 	instance string
 	data     int64
+
+	// Reference value of entity data belongs to, data without entity is returned for every entity
+	entity string
 }
 
 type mockAPI struct {
 	ClientFailure       bool
+	RetrieveClustersErr bool
 	RetrieveHostsErr    bool
 	RetrieveVMsErr      bool
+	RetrieveDsErr       bool
+	RetrievePoolsErr    bool
 	RetrieveCountersErr bool
+	RetrieveAlarmsErr   bool
+	RetrieveEventsErr   bool
 	PerfQueryErr        bool
+	QueryCountersErr    bool
+
+	// Set when session is closed
+	Closed bool
+
+	// Simulate session loss, either before collection or during perf query
+	SessionExpired           bool
+	ExpireSessionOnPerfQuery bool
+
+	// Return NotAuthenticated fault from next perf query, while session check still reports active session
+	FaultOnPerfQuery bool
+
+	// Number of session resets
+	Resets int
+
+	// Times events were retrieved since
+	EventsSince []time.Time
+
+	// Optional properties set for last collection
+	OptionalProperties map[string][]string
 }
 
+// UUID of datastore, used as an instance of host datastore counters
+const testDatastoreUUID = "58c7e1a4-1b5a2c3e-0a1b-001b21a2b3c4"
+
 var (
-	testHosts []mo.HostSystem
-	testVMs   map[string][]mo.VirtualMachine // map[Host Reference Name]Virtual Machines
+	testClusters   []mo.ClusterComputeResource
+	testHosts      []mo.HostSystem
+	testVMs        map[string][]mo.VirtualMachine // map[Host Reference Name]Virtual Machines
+	testDatastores []mo.Datastore
+	testPools      []mo.ResourcePool
+	testAlarms     []mo.Alarm
+	testEvents     []types.BaseEvent
+
+	// vCenter time events are read up to
+	testEventsUntil = time.Unix(1500001200, 0)
+
+	// Names of datacenters entities belong to, mapped by entity reference value
+	testDatacenterNames map[string]string
 
 	// Fixtures with server responses for counter queries with instances and example data
 	testCountersInstances []counterData
@@ -60,17 +108,84 @@ var (
 )
 
 func initFixtures() {
+	// Fixtures with configured clusters
+	testClusters = []mo.ClusterComputeResource{mo.ClusterComputeResource{}}
+	testClusters[0].Name = "cluster1"
+	testClusters[0].Self.Type = "ClusterComputeResource"
+	testClusters[0].Self.Value = "domain-c1"
+	testClusters[0].Summary = &types.ClusterComputeResourceSummary{
+		ComputeResourceSummary: types.ComputeResourceSummary{
+			TotalCpu:          20000,
+			TotalMemory:       8 * 1024 * 1024 * 1024,
+			NumHosts:          2,
+			NumEffectiveHosts: 1,
+		},
+	}
+
 	// Fixtures with all hosts and VMs available on cluster
 	testHosts = []mo.HostSystem{mo.HostSystem{}, mo.HostSystem{}}
 	testHosts[0].Name = "1.1.1.1"
 	testHosts[0].Self.Type = "HostSystem"
 	testHosts[0].Self.Value = "host-1"
+	testHosts[0].Parent = &testClusters[0].Self
 	testHosts[0].Hardware = &types.HostHardwareInfo{
 		MemorySize: 1234567890,
+		CpuInfo: types.HostCpuInfo{
+			NumCpuPackages: 2,
+			NumCpuCores:    16,
+			NumCpuThreads:  32,
+			Hz:             2400000000,
+		},
+		CpuPkg: []types.HostCpuPackage{
+			types.HostCpuPackage{Description: "Intel(R) Xeon(R) CPU E5-2630 v3 @ 2.40GHz"},
+		},
+	}
+	testHosts[0].Summary = types.HostListSummary{
+		Runtime: &types.HostRuntimeInfo{
+			ConnectionState:   types.HostSystemConnectionStateConnected,
+			PowerState:        types.HostSystemPowerStatePoweredOn,
+			InMaintenanceMode: true,
+		},
+		QuickStats: types.HostListSummaryQuickStats{
+			Uptime: 7200,
+		},
+		OverallStatus: types.ManagedEntityStatusYellow,
+	}
+	testHosts[0].Runtime.HealthSystemRuntime = &types.HealthSystemRuntime{
+		SystemHealthInfo: &types.HostSystemHealthInfo{
+			NumericSensorInfo: []types.HostNumericSensorInfo{
+				types.HostNumericSensorInfo{
+					Name:           "CPU1 Temp",
+					HealthState:    &types.ElementDescription{Key: "Green"},
+					CurrentReading: 4500,
+					UnitModifier:   -2,
+					BaseUnits:      "Degrees C",
+					SensorType:     "temperature",
+				},
+				types.HostNumericSensorInfo{
+					Name:           "Fan1",
+					HealthState:    &types.ElementDescription{Key: "Yellow"},
+					CurrentReading: 3600,
+					BaseUnits:      "RPM",
+					SensorType:     "fan",
+				},
+			},
+		},
+		HardwareStatusInfo: &types.HostHardwareStatusInfo{
+			StorageStatusInfo: []types.HostStorageElementInfo{
+				types.HostStorageElementInfo{
+					HostHardwareElementInfo: types.HostHardwareElementInfo{
+						Name:   "Controller 0",
+						Status: &types.ElementDescription{Key: "Red"},
+					},
+				},
+			},
+		},
 	}
 	testHosts[1].Name = "2.2.2.2"
 	testHosts[1].Self.Type = "HostSystem"
 	testHosts[1].Self.Value = "host-2"
+	testHosts[1].Parent = &types.ManagedObjectReference{Type: "ComputeResource", Value: "domain-s2"}
 	testHosts[1].Hardware = &types.HostHardwareInfo{
 		MemorySize: 4567890123,
 	}
@@ -84,6 +199,58 @@ func initFixtures() {
 		Runtime: types.VirtualMachineRuntimeInfo{
 			Host: &testHosts[0].Self,
 		},
+		Config: types.VirtualMachineConfigSummary{
+			NumCpu:       2,
+			MemorySizeMB: 4096,
+		},
+		Guest: &types.VirtualMachineGuestSummary{
+			ToolsRunningStatus:  "guestToolsRunning",
+			ToolsVersionStatus2: "guestToolsSupportedOld",
+		},
+		QuickStats: types.VirtualMachineQuickStats{
+			UptimeSeconds: 3600,
+		},
+		OverallStatus: types.ManagedEntityStatusGreen,
+	}
+	testVMs["host-1"][0].Summary.Runtime.PowerState = types.VirtualMachinePowerStatePoweredOn
+	consolidationNeeded := true
+	testVMs["host-1"][0].Summary.Runtime.ConsolidationNeeded = &consolidationNeeded
+	testVMs["host-1"][0].Snapshot = &types.VirtualMachineSnapshotInfo{
+		RootSnapshotList: []types.VirtualMachineSnapshotTree{
+			types.VirtualMachineSnapshotTree{
+				Name:       "snapshot1",
+				CreateTime: time.Unix(1500000000, 0),
+				ChildSnapshotList: []types.VirtualMachineSnapshotTree{
+					types.VirtualMachineSnapshotTree{
+						Name:       "snapshot2",
+						CreateTime: time.Unix(1500003600, 0),
+					},
+				},
+			},
+		},
+	}
+	testVMs["host-1"][0].Guest = &types.GuestInfo{
+		Disk: []types.GuestDiskInfo{
+			types.GuestDiskInfo{DiskPath: "/", Capacity: 20 * 1024 * 1024 * 1024, FreeSpace: 5 * 1024 * 1024 * 1024},
+			types.GuestDiskInfo{DiskPath: "/boot", Capacity: 512 * 1024 * 1024, FreeSpace: 256 * 1024 * 1024},
+		},
+	}
+	testVMs["host-1"][0].LayoutEx = &types.VirtualMachineFileLayoutEx{
+		File: []types.VirtualMachineFileLayoutExFileInfo{
+			types.VirtualMachineFileLayoutExFileInfo{Key: 1, Type: "diskExtent", Size: 10 * 1024 * 1024 * 1024},
+			types.VirtualMachineFileLayoutExFileInfo{Key: 2, Type: "diskExtent", Size: 512 * 1024 * 1024},
+			types.VirtualMachineFileLayoutExFileInfo{Key: 3, Type: "snapshotData", Size: 256 * 1024 * 1024},
+			types.VirtualMachineFileLayoutExFileInfo{Key: 4, Type: "config", Size: 4096},
+		},
+		Disk: []types.VirtualMachineFileLayoutExDiskLayout{
+			types.VirtualMachineFileLayoutExDiskLayout{
+				Key: 2000,
+				Chain: []types.VirtualMachineFileLayoutExDiskUnit{
+					types.VirtualMachineFileLayoutExDiskUnit{FileKey: []int32{1}},
+					types.VirtualMachineFileLayoutExDiskUnit{FileKey: []int32{2}},
+				},
+			},
+		},
 	}
 	testVMs["host-1"][1].Name = "VM2"
 	testVMs["host-1"][1].Self.Type = "VirtualMachine"
@@ -92,6 +259,147 @@ func initFixtures() {
 		Runtime: types.VirtualMachineRuntimeInfo{
 			Host: &testHosts[0].Self,
 		},
+		Config: types.VirtualMachineConfigSummary{
+			NumCpu: 2,
+		},
+	}
+
+	// Fixtures with all datastores available on cluster
+	testDatastores = []mo.Datastore{mo.Datastore{}}
+	testDatastores[0].Name = "DS1"
+	testDatastores[0].Self.Type = "Datastore"
+	testDatastores[0].Self.Value = "datastore-1"
+	testDatastores[0].Summary = types.DatastoreSummary{
+		Capacity:    10 * 1024 * 1024 * 1024,
+		FreeSpace:   4 * 1024 * 1024 * 1024,
+		Uncommitted: 1024 * 1024 * 1024,
+	}
+	testDatastores[0].Info = &types.VmfsDatastoreInfo{
+		DatastoreInfo: types.DatastoreInfo{Url: "ds:///vmfs/volumes/" + testDatastoreUUID + "/"},
+	}
+	testDatastores[0].Host = []types.DatastoreHostMount{
+		types.DatastoreHostMount{Key: testHosts[0].Self},
+		types.DatastoreHostMount{Key: testHosts[1].Self},
+	}
+
+	// Fixtures with alarm definitions and alarms triggered on cluster entities
+	testAlarms = []mo.Alarm{mo.Alarm{}, mo.Alarm{}}
+	testAlarms[0].Self.Type = "Alarm"
+	testAlarms[0].Self.Value = "alarm-1"
+	testAlarms[0].Info.Name = "Host connection and power state"
+	testAlarms[1].Self.Type = "Alarm"
+	testAlarms[1].Self.Value = "alarm-2"
+	testAlarms[1].Info.Name = "Datastore usage on disk"
+	acknowledged := true
+	testHosts[0].TriggeredAlarmState = []types.AlarmState{
+		types.AlarmState{
+			Key:           "alarm-1.host-1",
+			Entity:        testHosts[0].Self,
+			Alarm:         testAlarms[0].Self,
+			OverallStatus: types.ManagedEntityStatusRed,
+			Time:          time.Unix(1500000000, 0),
+		},
+	}
+	testDatastores[0].TriggeredAlarmState = []types.AlarmState{
+		types.AlarmState{
+			Key:           "alarm-2.datastore-1",
+			Entity:        testDatastores[0].Self,
+			Alarm:         testAlarms[1].Self,
+			OverallStatus: types.ManagedEntityStatusYellow,
+			Time:          time.Unix(1500000600, 0),
+			Acknowledged:  &acknowledged,
+		},
+	}
+
+	// Fixtures with events created since previous collection, including events from other cluster and standalone host
+	clusterArg := &types.ComputeResourceEventArgument{ComputeResource: testClusters[0].Self}
+	clusterArg.Name = testClusters[0].Name
+	otherClusterArg := &types.ComputeResourceEventArgument{
+		ComputeResource: types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "domain-c2"},
+	}
+	otherClusterArg.Name = "cluster2"
+	standaloneArg := &types.ComputeResourceEventArgument{ComputeResource: *testHosts[1].Parent}
+	standaloneArg.Name = "2.2.2.2"
+	dc1Arg := &types.DatacenterEventArgument{Datacenter: types.ManagedObjectReference{Type: "Datacenter", Value: "datacenter-1"}}
+	dc1Arg.Name = "DC1"
+	vm1Arg := &types.VmEventArgument{Vm: testVMs["host-1"][0].Self}
+	vm1Arg.Name = "VM1"
+	host1Arg := &types.HostEventArgument{Host: testHosts[0].Self}
+	host1Arg.Name = "1.1.1.1"
+	testEvents = []types.BaseEvent{
+		&types.VmPoweredOnEvent{VmEvent: types.VmEvent{Event: types.Event{
+			Key:                  101,
+			CreatedTime:          time.Unix(1500000000, 0),
+			UserName:             "admin",
+			Datacenter:           dc1Arg,
+			ComputeResource:      clusterArg,
+			Host:                 host1Arg,
+			Vm:                   vm1Arg,
+			FullFormattedMessage: "VM1 on 1.1.1.1 is powered on",
+		}}},
+		&types.VmPoweredOnEvent{VmEvent: types.VmEvent{Event: types.Event{
+			Key:             102,
+			ComputeResource: otherClusterArg,
+		}}},
+		&types.BadUsernameSessionEvent{SessionEvent: types.SessionEvent{Event: types.Event{
+			Key:                  103,
+			FullFormattedMessage: "Cannot login user@127.0.0.1: incorrect user name or password",
+		}}},
+		&types.EventEx{Event: types.Event{Key: 104, ComputeResource: clusterArg}, EventTypeId: "com.vmware.vc.ha.VmRestartedByHAEvent"},
+		&types.HostShutdownEvent{HostEvent: types.HostEvent{Event: types.Event{Key: 105, ComputeResource: standaloneArg}}},
+	}
+
+	// Fixtures with all resource pools available on cluster
+	testPools = []mo.ResourcePool{mo.ResourcePool{}, mo.ResourcePool{}}
+	testPools[0].Name = "Resources"
+	testPools[0].Self.Type = "ResourcePool"
+	testPools[0].Self.Value = "resgroup-1"
+	testPools[0].Owner = testClusters[0].Self
+	testPools[0].Vm = []types.ManagedObjectReference{testVMs["host-1"][1].Self}
+	testPools[0].Summary = &types.ResourcePoolSummary{
+		Config: types.ResourceConfigSpec{
+			CpuAllocation:    &types.ResourceAllocationInfo{Reservation: 20000, Limit: -1},
+			MemoryAllocation: &types.ResourceAllocationInfo{Reservation: 8192, Limit: -1},
+		},
+	}
+	testPools[1].Name = "Pool1"
+	testPools[1].Self.Type = "ResourcePool"
+	testPools[1].Self.Value = "resgroup-2"
+	testPools[1].Owner = testClusters[0].Self
+	testPools[1].Vm = []types.ManagedObjectReference{testVMs["host-1"][0].Self}
+	testPools[1].Summary = &types.ResourcePoolSummary{
+		Config: types.ResourceConfigSpec{
+			CpuAllocation: &types.ResourceAllocationInfo{
+				Reservation: 1000,
+				Limit:       4000,
+				Shares:      &types.SharesInfo{Shares: 4000, Level: types.SharesLevelNormal},
+			},
+			MemoryAllocation: &types.ResourceAllocationInfo{
+				Reservation: 1024,
+				Limit:       2048,
+				Shares:      &types.SharesInfo{Shares: 20480, Level: types.SharesLevelNormal},
+			},
+		},
+		QuickStats: &types.ResourcePoolQuickStats{
+			OverallCpuUsage:  1500,
+			GuestMemoryUsage: 512,
+			HostMemoryUsage:  1536,
+			OverheadMemory:   64,
+		},
+	}
+	testVMs["host-1"][0].ResourcePool = &testPools[1].Self
+	testVMs["host-1"][1].ResourcePool = &testPools[0].Self
+
+	// All entities from fixtures belong to the same datacenter
+	testDatacenterNames = map[string]string{}
+	for _, cluster := range testClusters {
+		testDatacenterNames[cluster.Self.Value] = "DC1"
+	}
+	for _, host := range testHosts {
+		testDatacenterNames[host.Self.Value] = "DC1"
+	}
+	for _, ds := range testDatastores {
+		testDatacenterNames[ds.Self.Value] = "DC1"
 	}
 
 	// Fixtures with all counter instances and data on server
@@ -122,30 +430,126 @@ func initFixtures() {
 		counterData{key: 13, instance: "0", data: 1100},
 		counterData{key: 14, instance: "0", data: 1200},
 		counterData{key: 15, instance: "0", data: 1300},
+
+		// datastore
+		counterData{key: 16, instance: testDatastoreUUID, data: 1400},
+		counterData{key: 17, instance: testDatastoreUUID, data: 1500},
+		counterData{key: 18, instance: testDatastoreUUID, data: 1600},
+		counterData{key: 19, instance: testDatastoreUUID, data: 1700},
+		counterData{key: 20, instance: testDatastoreUUID, data: 1800},
+		counterData{key: 21, instance: testDatastoreUUID, data: 1900},
+
+		// vm cpu
+		counterData{key: 22, instance: "", data: 2000},
+		counterData{key: 22, instance: "0", data: 1000},
+		counterData{key: 23, instance: "", data: 2000},
+		counterData{key: 23, instance: "0", data: 1000},
+		counterData{key: 24, instance: "", data: 400},
+		counterData{key: 25, instance: "", data: 19000},
+		counterData{key: 26, instance: "", data: 10},
+
+		// vm mem
+		counterData{key: 27, instance: "", data: 204800},
+		counterData{key: 28, instance: "", data: 102400},
+		counterData{key: 29, instance: "", data: 51200},
+		counterData{key: 30, instance: "", data: 10240},
+		counterData{key: 31, instance: "", data: 409600},
+		counterData{key: 32, instance: "", data: 30720},
+		counterData{key: 33, instance: "", data: 512},
+		counterData{key: 34, instance: "", data: 2048},
+
+		// vm net
+		counterData{key: 35, instance: "", data: 3},
+		counterData{key: 35, instance: "4000", data: 2},
+		counterData{key: 35, instance: "4001", data: 1},
+		counterData{key: 36, instance: "4000", data: 4},
+		counterData{key: 37, instance: "4000", data: 5},
+		counterData{key: 38, instance: "4000", data: 6},
+		counterData{key: 39, instance: "4000", data: 7},
+		counterData{key: 40, instance: "4000", data: 8},
+		counterData{key: 41, instance: "4000", data: 9},
+		counterData{key: 41, instance: "", data: 9},
+
+		// host disk, storageAdapter and storagePath
+		counterData{key: 42, instance: "naa.1", data: 10},
+		counterData{key: 42, instance: "naa.2", data: 20},
+		counterData{key: 43, instance: "naa.1", data: 3},
+		counterData{key: 44, instance: "naa.1", data: 1},
+		counterData{key: 45, instance: "vmhba0", data: 15},
+		counterData{key: 46, instance: "vmhba0:C0:T0:L0", data: 25},
+
+		// power
+		counterData{key: 47, instance: "", data: 250},
+		counterData{key: 48, instance: "", data: 500},
+		counterData{key: 49, instance: "", data: 5000},
+
+		// cluster
+		counterData{key: 50, instance: "", data: 18000},
+		counterData{key: 51, instance: "", data: 7000},
+		counterData{key: 52, instance: "", data: 1},
+		counterData{key: 53, instance: "", data: 4250},
 	}
 
 	// Fixtures with all available counters on server
 	testCountersInfo = []counterInfo{
-		counterInfo{key: 1, group: "cpu", name: "usage", rollup: "average"},
-		counterInfo{key: 2, group: "cpu", name: "latency", rollup: "average"},
-		counterInfo{key: 3, group: "rescpu", name: "actav1", rollup: "latest"},
-		counterInfo{key: 4, group: "mem", name: "consumed", rollup: "average"},
-		counterInfo{key: 5, group: "mem", name: "swapused", rollup: "average"},
-		counterInfo{key: 6, group: "net", name: "bytesTx", rollup: "average"},
-		counterInfo{key: 7, group: "net", name: "bytesRx", rollup: "average"},
-		counterInfo{key: 8, group: "net", name: "packetsTx", rollup: "summation"},
-		counterInfo{key: 9, group: "net", name: "packetsRx", rollup: "summation"},
-		counterInfo{key: 10, group: "virtualDisk", name: "numberReadAveraged", rollup: "average"},
-		counterInfo{key: 11, group: "virtualDisk", name: "numberWriteAveraged", rollup: "average"},
-		counterInfo{key: 12, group: "virtualDisk", name: "read", rollup: "average"},
-		counterInfo{key: 13, group: "virtualDisk", name: "write", rollup: "average"},
-		counterInfo{key: 14, group: "virtualDisk", name: "totalReadLatency", rollup: "average"},
-		counterInfo{key: 15, group: "virtualDisk", name: "totalWriteLatency", rollup: "average"},
+		counterInfo{key: 1, group: "cpu", name: "usage", rollup: "average", level: 1},
+		counterInfo{key: 2, group: "cpu", name: "latency", rollup: "average", level: 1},
+		counterInfo{key: 3, group: "rescpu", name: "actav1", rollup: "latest", level: 1},
+		counterInfo{key: 4, group: "mem", name: "consumed", rollup: "average", level: 1},
+		counterInfo{key: 5, group: "mem", name: "swapused", rollup: "average", level: 1},
+		counterInfo{key: 6, group: "net", name: "bytesTx", rollup: "average", level: 1},
+		counterInfo{key: 7, group: "net", name: "bytesRx", rollup: "average", level: 1},
+		counterInfo{key: 8, group: "net", name: "packetsTx", rollup: "summation", level: 1},
+		counterInfo{key: 9, group: "net", name: "packetsRx", rollup: "summation", level: 1},
+		counterInfo{key: 10, group: "virtualDisk", name: "numberReadAveraged", rollup: "average", level: 1},
+		counterInfo{key: 11, group: "virtualDisk", name: "numberWriteAveraged", rollup: "average", level: 1},
+		counterInfo{key: 12, group: "virtualDisk", name: "read", rollup: "average", level: 1},
+		counterInfo{key: 13, group: "virtualDisk", name: "write", rollup: "average", level: 1},
+		counterInfo{key: 14, group: "virtualDisk", name: "totalReadLatency", rollup: "average", level: 1},
+		counterInfo{key: 15, group: "virtualDisk", name: "totalWriteLatency", rollup: "average", level: 1},
+		counterInfo{key: 16, group: "datastore", name: "numberReadAveraged", rollup: "average", level: 1},
+		counterInfo{key: 17, group: "datastore", name: "numberWriteAveraged", rollup: "average", level: 1},
+		counterInfo{key: 18, group: "datastore", name: "read", rollup: "average", level: 1},
+		counterInfo{key: 19, group: "datastore", name: "write", rollup: "average", level: 1},
+		counterInfo{key: 20, group: "datastore", name: "totalReadLatency", rollup: "average", level: 1},
+		counterInfo{key: 21, group: "datastore", name: "totalWriteLatency", rollup: "average", level: 1},
+		counterInfo{key: 22, group: "cpu", name: "usagemhz", rollup: "average", level: 1},
+		counterInfo{key: 23, group: "cpu", name: "ready", rollup: "summation", level: 1},
+		counterInfo{key: 24, group: "cpu", name: "costop", rollup: "summation", level: 1},
+		counterInfo{key: 25, group: "cpu", name: "wait", rollup: "summation", level: 1},
+		counterInfo{key: 26, group: "cpu", name: "swapwait", rollup: "summation", level: 1},
+		counterInfo{key: 27, group: "mem", name: "active", rollup: "average", level: 1},
+		counterInfo{key: 28, group: "mem", name: "vmmemctl", rollup: "average", level: 1},
+		counterInfo{key: 29, group: "mem", name: "swapped", rollup: "average", level: 1},
+		counterInfo{key: 30, group: "mem", name: "compressed", rollup: "average", level: 1},
+		counterInfo{key: 31, group: "mem", name: "granted", rollup: "average", level: 1},
+		counterInfo{key: 32, group: "mem", name: "overhead", rollup: "average", level: 1},
+		counterInfo{key: 33, group: "mem", name: "swapinRate", rollup: "average", level: 1},
+		counterInfo{key: 34, group: "mem", name: "swapoutRate", rollup: "average", level: 1},
+		counterInfo{key: 35, group: "net", name: "droppedRx", rollup: "summation", level: 1},
+		counterInfo{key: 36, group: "net", name: "droppedTx", rollup: "summation", level: 1},
+		counterInfo{key: 37, group: "net", name: "broadcastRx", rollup: "summation", level: 1},
+		counterInfo{key: 38, group: "net", name: "broadcastTx", rollup: "summation", level: 1},
+		counterInfo{key: 39, group: "net", name: "multicastRx", rollup: "summation", level: 1},
+		counterInfo{key: 40, group: "net", name: "multicastTx", rollup: "summation", level: 1},
+		counterInfo{key: 41, group: "net", name: "usage", rollup: "average", level: 1},
+		counterInfo{key: 42, group: "disk", name: "deviceLatency", rollup: "average", level: 1},
+		counterInfo{key: 43, group: "disk", name: "queueLatency", rollup: "average", level: 1},
+		counterInfo{key: 44, group: "disk", name: "busResets", rollup: "summation", level: 1},
+		counterInfo{key: 45, group: "storageAdapter", name: "totalReadLatency", rollup: "average", level: 1},
+		counterInfo{key: 46, group: "storagePath", name: "read", rollup: "average", level: 1},
+		counterInfo{key: 47, group: "power", name: "power", rollup: "average", level: 1},
+		counterInfo{key: 48, group: "power", name: "powerCap", rollup: "average", level: 1},
+		counterInfo{key: 49, group: "power", name: "energy", rollup: "summation", level: 1},
+		counterInfo{key: 50, group: "clusterServices", name: "effectivecpu", rollup: "average", level: 1, entity: "cluster"},
+		counterInfo{key: 51, group: "clusterServices", name: "effectivemem", rollup: "average", level: 1, entity: "cluster"},
+		counterInfo{key: 52, group: "clusterServices", name: "failover", rollup: "latest", level: 2, entity: "cluster"},
+		counterInfo{key: 53, group: "mem", name: "usage", rollup: "average", level: 1},
 	}
 }
 
 // Init initializes all necessary objects to send API calls to vSphere
-func (a *mockAPI) Init(ctx context.Context, url, username, password, clusterName string, datacenterName string, insecure bool) error {
+func (a *mockAPI) Init(ctx context.Context, cfg connectionConfig) error {
 	if a.ClientFailure {
 		return fmt.Errorf("unable to initialize client")
 	}
@@ -155,6 +559,24 @@ func (a *mockAPI) Init(ctx context.Context, url, username, password, clusterName
 func (a *mockAPI) ClearCache() {
 }
 
+func (a *mockAPI) SetOptionalProperties(props map[string][]string) {
+	a.OptionalProperties = props
+}
+
+func (a *mockAPI) Close(ctx context.Context) error {
+	a.Closed = true
+	return nil
+}
+
+func (a *mockAPI) SessionActive(ctx context.Context) (bool, error) {
+	return !a.SessionExpired, nil
+}
+
+func (a *mockAPI) Reset(ctx context.Context) {
+	a.SessionExpired = false
+	a.Resets++
+}
+
 // RetrieveCounters retrieves vSphere cluster metric list that are available for user
 func (a *mockAPI) RetrieveCounters(ctx context.Context) ([]types.PerfCounterInfo, error) {
 	if a.RetrieveCountersErr {
@@ -167,20 +589,54 @@ func (a *mockAPI) RetrieveCounters(ctx context.Context) ([]types.PerfCounterInfo
 			Key: c.key,
 			NameInfo: &types.ElementDescription{
 				Key: c.name,
+				Description: types.Description{
+					Summary: c.group + " " + c.name,
+				},
 			},
 			GroupInfo: &types.ElementDescription{
 				Key: c.group,
 			},
+			UnitInfo: &types.ElementDescription{
+				Key: "number",
+			},
 			RollupType: types.PerfSummaryType(c.rollup),
+			Level:      c.level,
 		})
 	}
 
 	return testCounters, nil
 }
 
-// RetrieveCounters retrieves vSphere cluster datastore list that are available for user
+// RetrieveClusters retrieves configured vSphere clusters
+func (a *mockAPI) RetrieveClusters(ctx context.Context) ([]mo.ClusterComputeResource, error) {
+	if a.RetrieveClustersErr {
+		return nil, fmt.Errorf("test error")
+	}
+	return testClusters, nil
+}
+
+// RetrieveDatacenterName retrieves name of datacenter configured compute resource, host or datastore belongs to
+func (a *mockAPI) RetrieveDatacenterName(ctx context.Context, ref types.ManagedObjectReference) (string, error) {
+	if name, ok := testDatacenterNames[ref.Value]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("test error")
+}
+
+// RetrievePerfIntervals retrieves historical intervals configured on vCenter
+func (a *mockAPI) RetrievePerfIntervals(ctx context.Context) ([]types.PerfInterval, error) {
+	return []types.PerfInterval{
+		types.PerfInterval{SamplingPeriod: 300, Level: 1, Enabled: true},
+		types.PerfInterval{SamplingPeriod: 1800, Level: 1, Enabled: false},
+	}, nil
+}
+
+// RetrieveDatastores retrieves vSphere cluster datastore list that are available for user
 func (a *mockAPI) RetrieveDatastores(ctx context.Context) ([]mo.Datastore, error) {
-	return nil, nil
+	if a.RetrieveDsErr {
+		return nil, fmt.Errorf("test error")
+	}
+	return testDatastores, nil
 }
 
 // RetrieveHosts finds all hosts on given vSphere cluster
@@ -199,6 +655,54 @@ func (a *mockAPI) RetrieveVMs(ctx context.Context, host mo.HostSystem) ([]mo.Vir
 	return testVMs[host.Reference().Value], nil
 }
 
+// RetrieveResourcePools retrieves all resource pools for cluster
+func (a *mockAPI) RetrieveResourcePools(ctx context.Context) ([]mo.ResourcePool, error) {
+	if a.RetrievePoolsErr {
+		return nil, fmt.Errorf("test error")
+	}
+	return testPools, nil
+}
+
+// RetrieveAlarms retrieves definitions of alarms with given references
+func (a *mockAPI) RetrieveAlarms(ctx context.Context, refs []types.ManagedObjectReference) ([]mo.Alarm, error) {
+	if a.RetrieveAlarmsErr {
+		return nil, fmt.Errorf("test error")
+	}
+	result := []mo.Alarm{}
+	for _, ref := range refs {
+		for _, alarm := range testAlarms {
+			if alarm.Self.Value == ref.Value {
+				result = append(result, alarm)
+			}
+		}
+	}
+	return result, nil
+}
+
+// RetrieveEvents retrieves events created on vCenter since given time
+// Test events are returned for any time, and event retrieval times are recorded
+func (a *mockAPI) RetrieveEvents(ctx context.Context, since time.Time) ([]types.BaseEvent, time.Time, error) {
+	if a.RetrieveEventsErr {
+		return nil, time.Time{}, fmt.Errorf("test error")
+	}
+	a.EventsSince = append(a.EventsSince, since)
+	return testEvents, testEventsUntil, nil
+}
+
+// QueryAvailableCounters returns keys of test counters available for hosts and VMs
+func (a *mockAPI) QueryAvailableCounters(ctx context.Context, entity types.ManagedObjectReference) ([]int32, error) {
+	if a.QueryCountersErr {
+		return nil, fmt.Errorf("test error")
+	}
+	keys := []int32{}
+	for _, c := range testCountersInfo {
+		if c.entity == "" {
+			keys = append(keys, c.key)
+		}
+	}
+	return keys, nil
+}
+
 // PerfQuery retrieves all metric data for provided query specs
 // This method builds query perf response from provided query specs using
 // testCountersInstances fixtures
@@ -207,6 +711,15 @@ func (a *mockAPI) PerfQuery(ctx context.Context, querySpecs []types.PerfQuerySpe
 		return nil, fmt.Errorf("test error")
 	}
 
+	if a.ExpireSessionOnPerfQuery {
+		a.ExpireSessionOnPerfQuery = false
+		a.SessionExpired = true
+	}
+	if a.SessionExpired || a.FaultOnPerfQuery {
+		a.FaultOnPerfQuery = false
+		return nil, soap.WrapVimFault(&types.NotAuthenticated{})
+	}
+
 	result := &types.QueryPerfResponse{
 		Returnval: []types.BasePerfEntityMetricBase{},
 	}
@@ -219,8 +732,19 @@ func (a *mockAPI) PerfQuery(ctx context.Context, querySpecs []types.PerfQuerySpe
 			entity.(*types.PerfEntityMetric).Entity = querySpec.Entity
 			entity.(*types.PerfEntityMetric).Value = []types.BasePerfMetricSeries{}
 
+			// Entity specific fixtures take precedence over fixtures returned for every entity
+			entityData := false
+			for _, data := range testCountersInstances {
+				if data.key == metricID.CounterId && data.entity == querySpec.Entity.Value {
+					entityData = true
+				}
+			}
+
 			// Loop through testCountersInstances and match fixtures
 			for _, data := range testCountersInstances {
+				if data.entity != querySpec.Entity.Value && (entityData || data.entity != "") {
+					continue
+				}
 				if data.key == metricID.CounterId {
 					if data.instance == metricID.Instance || metricID.Instance == "*" {
 						// Build metric instance and data
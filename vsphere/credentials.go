@@ -0,0 +1,165 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// credentialFile is content of credential file, re-read when file modification time or size changes
+type credentialFile struct {
+	modTime time.Time
+	size    int64
+	content string
+}
+
+// credentialFiles caches content of password and netrc files between collections
+type credentialFiles map[string]*credentialFile
+
+// read returns content of file at given path, reading it again only if file has changed since previous read
+func (f credentialFiles) read(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if cached, ok := f[path]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.content, nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	f[path] = &credentialFile{modTime: info.ModTime(), size: info.Size(), content: string(content)}
+	return string(content), nil
+}
+
+// readCredentials returns username and password for vCenter at given URL
+// Credentials are taken from first configured source, in order: environment variables (usernameEnv, passwordEnv),
+// password file (passwordFile), netrc file (netrcFile) and plaintext config (username, password)
+func (f credentialFiles) readCredentials(cfg plugin.Config, vURL string) (string, string, error) {
+	username := optionalString(cfg, "username")
+	password := optionalString(cfg, "password")
+
+	if netrcFile := optionalString(cfg, "netrcFile"); netrcFile != "" {
+		content, err := f.read(netrcFile)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to read netrc file: %v", err)
+		}
+
+		u, err := url.Parse(vURL)
+		if err != nil {
+			return "", "", err
+		}
+		login, pass, ok := parseNetrc(content, u.Hostname())
+		if !ok {
+			return "", "", fmt.Errorf("no netrc entry for %s", u.Hostname())
+		}
+		username, password = login, pass
+	}
+
+	if passwordFile := optionalString(cfg, "passwordFile"); passwordFile != "" {
+		content, err := f.read(passwordFile)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to read password file: %v", err)
+		}
+		password = strings.TrimRight(content, "\r\n")
+	}
+
+	if usernameEnv := optionalString(cfg, "usernameEnv"); usernameEnv != "" {
+		username = os.Getenv(usernameEnv)
+	}
+	if passwordEnv := optionalString(cfg, "passwordEnv"); passwordEnv != "" {
+		password = os.Getenv(passwordEnv)
+	}
+
+	if username == "" {
+		return "", "", fmt.Errorf("username is not configured")
+	}
+	if password == "" {
+		return "", "", fmt.Errorf("password is not configured")
+	}
+	return username, password, nil
+}
+
+// parseNetrc finds login and password for given machine in netrc file content, falling back to default entry
+func parseNetrc(content, machine string) (string, string, bool) {
+	type entry struct {
+		login, password string
+	}
+	var found, def *entry
+	var current *entry
+
+	fields := strings.Fields(content)
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			current = nil
+			if i+1 < len(fields) {
+				i++
+				if fields[i] == machine && found == nil {
+					found = &entry{}
+					current = found
+				}
+			}
+		case "default":
+			current = nil
+			if def == nil {
+				def = &entry{}
+				current = def
+			}
+		case "login", "password", "account":
+			if i+1 < len(fields) {
+				i++
+				if current != nil && fields[i-1] == "login" {
+					current.login = fields[i]
+				}
+				if current != nil && fields[i-1] == "password" {
+					current.password = fields[i]
+				}
+			}
+		}
+	}
+
+	if found == nil {
+		found = def
+	}
+	if found == nil {
+		return "", "", false
+	}
+	return found.login, found.password, true
+}
+
+// optionalString returns string config value, or empty string if it is not set
+func optionalString(cfg plugin.Config, key string) string {
+	value, err := cfg.GetString(key)
+	if err != nil {
+		return ""
+	}
+	return value
+}
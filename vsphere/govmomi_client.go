@@ -22,9 +22,14 @@ package vsphere
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -33,19 +38,47 @@ const (
 	// and ignore historical data. See README.md for more details.
 	defaultIntervalID = 20
 
+	// Historical IntervalId for QueryPerf(). Used for entities which do not support real-time statistics
+	// (i.e. clusters). Returns the most recent 5-minute sample.
+	historicalIntervalID = 300
+
 	// Default metric instance for QueryPerf(). Some counters have more than one instance
 	// (for example, for each CPU core). Asterisk specifies all the instances. Empty field specifies aggregated instances.
 	defaultMetricInstance = "*"
+
+	// Maximum number of events read from event history collector at once
+	eventPageSize = 1000
+
+	// Time after which event cursor of task which stopped collecting events is removed
+	eventCursorIdleTimeout = 24 * time.Hour
+
+	// Time after which unused pooled vSphere session is logged out and removed from connection pool
+	connectionIdleTimeout = 30 * time.Minute
+
+	// Maximum time of logging out of session which is being replaced, so unreachable vCenter does not block new login
+	logoutTimeout = 10 * time.Second
+
+	// Interval of requests keeping vSphere session alive between collections, shorter than default vCenter session timeout (30 minutes)
+	keepAliveInterval = 10 * time.Minute
 )
 
 // API - vSphere API interface for testing purposes
 type API interface {
 	// Initialize all necessary objects to send API calls to vSphere
-	Init(ctx context.Context, url, username, password, clusterName string, datacenterName string, insecure bool) error
+	Init(ctx context.Context, cfg connectionConfig) error
 
 	// RetrieveCounters retrieves vSphere cluster metric list that are available for user
 	RetrieveCounters(ctx context.Context) ([]types.PerfCounterInfo, error)
 
+	// Get configured clusters
+	RetrieveClusters(ctx context.Context) ([]mo.ClusterComputeResource, error)
+
+	// Get name of datacenter configured compute resource, host or datastore belongs to
+	RetrieveDatacenterName(ctx context.Context, ref types.ManagedObjectReference) (string, error)
+
+	// RetrievePerfIntervals retrieves historical intervals configured on vCenter
+	RetrievePerfIntervals(ctx context.Context) ([]types.PerfInterval, error)
+
 	// Get all datastores for cluster
 	RetrieveDatastores(ctx context.Context) ([]mo.Datastore, error)
 
@@ -55,16 +88,82 @@ type API interface {
 	// Find all VMs for given host
 	RetrieveVMs(ctx context.Context, host mo.HostSystem) ([]mo.VirtualMachine, error)
 
+	// Get all resource pools for cluster
+	RetrieveResourcePools(ctx context.Context) ([]mo.ResourcePool, error)
+
+	// Get definitions of alarms with given references
+	RetrieveAlarms(ctx context.Context, refs []types.ManagedObjectReference) ([]mo.Alarm, error)
+
+	// Get events created on vCenter since previous call
+	RetrieveEvents(ctx context.Context, since time.Time) ([]types.BaseEvent, time.Time, error)
+
 	// Call performance query to retrieve perf data
 	PerfQuery(ctx context.Context, querySpecs []types.PerfQuerySpec) (*types.QueryPerfResponse, error)
 
+	// Get keys of perf counters available at real-time interval for given entity
+	QueryAvailableCounters(ctx context.Context, entity types.ManagedObjectReference) ([]int32, error)
+
 	// Clear API retrieve cache
 	ClearCache()
+
+	// Set optional properties retrieved for hosts and VMs, mapped by entity type ("host" or "vm")
+	SetOptionalProperties(props map[string][]string)
+
+	// Close vSphere session
+	Close(ctx context.Context) error
+
+	// Check whether vSphere session is still authenticated, keeping it alive
+	SessionActive(ctx context.Context) (bool, error)
+
+	// Log out of vSphere session and drop all objects built on it, so next Init logs in again
+	Reset(ctx context.Context)
+}
+
+// connectionConfig identifies pooled vSphere session, tasks with equal connection configs share one session
+type connectionConfig struct {
+	url                string
+	username           string
+	password           string
+	clusterName        string
+	standaloneHostName string
+	datacenterName     string
+	insecure           bool
+
+	// Authentication mode (password or SSO token) and SSO token service URL
+	authMode string
+	ssoURL   string
+
+	// TLS trust and client certificate settings
+	caFile       string
+	thumbprint   string
+	certFile     string
+	keyFile      string
+	extensionKey string
+}
+
+// pooledConnection is vSphere session kept in connection pool
+type pooledConnection struct {
+	api      API
+	lastUsed time.Time
 }
 
 // govmomiClient is proxy for API calls, providing more functionality and allowing to mock API calls separately for testing
 type govmomiClient struct {
+	// API used by currently collected task, and its connection config
 	api API
+	key connectionConfig
+
+	// Factory of API instances for new pooled sessions
+	newAPI func() API
+
+	// Sessions mapped by connection config, allowing to collect from multiple vCenters and clusters
+	pool map[connectionConfig]*pooledConnection
+
+	// Guards pool, selected session and session resets
+	mu sync.Mutex
+
+	// Credential files read by tasks, re-read when changed, so rotated password opens new session
+	credentialFiles credentialFiles
 }
 
 func (c *govmomiClient) Init(ctx context.Context, cfg plugin.Config) error {
@@ -72,30 +171,168 @@ func (c *govmomiClient) Init(ctx context.Context, cfg plugin.Config) error {
 	if err != nil {
 		return err
 	}
-	username, err := cfg.GetString("username")
-	if err != nil {
-		return err
+	key := connectionConfig{
+		url:          url,
+		caFile:       optionalString(cfg, "caFile"),
+		thumbprint:   optionalString(cfg, "thumbprint"),
+		certFile:     optionalString(cfg, "certFile"),
+		keyFile:      optionalString(cfg, "keyFile"),
+		extensionKey: optionalString(cfg, "extensionKey"),
 	}
-	password, err := cfg.GetString("password")
-	if err != nil {
-		return err
+	key.authMode, key.ssoURL = optionalString(cfg, "authMode"), optionalString(cfg, "ssoURL")
+	if key.authMode == "" {
+		key.authMode = authModePassword
+	}
+	if key.authMode != authModePassword && key.authMode != authModeToken {
+		return fmt.Errorf("invalid authMode %s, expected %s or %s", key.authMode, authModePassword, authModeToken)
+	}
+	if key.authMode == authModeToken && key.extensionKey != "" {
+		return fmt.Errorf("extensionKey cannot be used with %s authMode", authModeToken)
+	}
+	if (key.certFile == "") != (key.keyFile == "") {
+		return fmt.Errorf("certFile and keyFile have to be configured together")
 	}
 
-	insecure, err := cfg.GetBool("insecure")
-	if err != nil {
-		return err
+	// Solution user (extension) logs in with client certificate, so username and password are not needed
+	if key.extensionKey != "" {
+		if key.certFile == "" {
+			return fmt.Errorf("extensionKey requires certFile and keyFile")
+		}
+	} else {
+		if c.credentialFiles == nil {
+			c.credentialFiles = credentialFiles{}
+		}
+		key.username, key.password, err = c.credentialFiles.readCredentials(cfg, url)
+		if err != nil {
+			return err
+		}
 	}
-	clusterName, err := cfg.GetString("clusterName")
+
+	insecure, err := cfg.GetBool("insecure")
 	if err != nil {
 		return err
 	}
 
+	// Clusters and standalone hosts are optional, all clusters are collected if neither of them is configured
+	clusterName := optionalString(cfg, "clusterName")
+	standaloneHostName := optionalString(cfg, "standaloneHostName")
+
 	datacenterName, err := cfg.GetString("datacenterName")
 	if err != nil {
 		return err
 	}
 
-	return c.api.Init(ctx, url, username, password, clusterName, datacenterName, insecure)
+	key.clusterName = clusterName
+	key.standaloneHostName = standaloneHostName
+	key.datacenterName = datacenterName
+	key.insecure = insecure
+	return c.connect(ctx, key, time.Now())
+}
+
+// connect selects pooled session for given connection config, creating new one if config was not seen before,
+// and reaps sessions unused for longer than connectionIdleTimeout
+func (c *govmomiClient) connect(ctx context.Context, key connectionConfig, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pool == nil {
+		c.pool = map[connectionConfig]*pooledConnection{}
+	}
+
+	conn, ok := c.pool[key]
+	if !ok {
+		api := c.newAPI()
+		if err := api.Init(ctx, key); err != nil {
+			api.Close(ctx)
+			return err
+		}
+		conn = &pooledConnection{api: api}
+		c.pool[key] = conn
+	} else if active, err := conn.api.SessionActive(ctx); err != nil || !active {
+		// Session expired or vCenter was restarted since previous collection
+		conn.api.Reset(ctx)
+		if err := conn.api.Init(ctx, key); err != nil {
+			return err
+		}
+	}
+	conn.lastUsed = now
+	c.api = conn.api
+	c.key = key
+
+	for k, idle := range c.pool {
+		if now.Sub(idle.lastUsed) > connectionIdleTimeout {
+			// Session might be already expired on vCenter side, so logout error is irrelevant
+			idle.api.Close(ctx)
+			delete(c.pool, k)
+		}
+	}
+
+	return nil
+}
+
+// Recover checks session of currently collected task after API call failed with given error, and logs in again if session was lost.
+// Session is checked on vCenter only if error is not NotAuthenticated fault.
+// Returns true if new session was established, so failed operation can be retried
+func (c *govmomiClient) Recover(ctx context.Context, err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !isNotAuthenticated(err) {
+		if active, err := c.api.SessionActive(ctx); err == nil && active {
+			return false
+		}
+	}
+
+	c.api.Reset(ctx)
+	return c.api.Init(ctx, c.key) == nil
+}
+
+// wrappedError adds context to error returned by vSphere API, keeping the original error, so SOAP faults can be inspected
+type wrappedError struct {
+	context string
+	err     error
+}
+
+func (e *wrappedError) Error() string {
+	return fmt.Sprintf("%s: %v", e.context, e.err)
+}
+
+// wrapError returns error with given context, from which original error can be retrieved by isNotAuthenticated
+func wrapError(context string, err error) error {
+	return &wrappedError{context: context, err: err}
+}
+
+// isNotAuthenticated checks whether given error (possibly wrapped) is NotAuthenticated fault, returned by vSphere API
+// when session has expired or vCenter was restarted
+func isNotAuthenticated(err error) bool {
+	for {
+		wrapped, ok := err.(*wrappedError)
+		if !ok {
+			break
+		}
+		err = wrapped.err
+	}
+
+	if soap.IsSoapFault(err) {
+		_, ok := soap.ToSoapFault(err).VimFault().(types.NotAuthenticated)
+		return ok
+	}
+	if soap.IsVimFault(err) {
+		_, ok := soap.ToVimFault(err).(*types.NotAuthenticated)
+		return ok
+	}
+	return false
+}
+
+// splitNames splits comma-separated list of names (or wildcard patterns) given in config, skipping empty entries
+func splitNames(names string) []string {
+	results := []string{}
+	for _, n := range strings.Split(names, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			results = append(results, n)
+		}
+	}
+	return results
 }
 
 func (c *govmomiClient) PerfQuery(ctx context.Context, querySpecs []types.PerfQuerySpec) (*types.QueryPerfResponse, error) {
@@ -106,12 +343,59 @@ func (c *govmomiClient) RetrieveCounters(ctx context.Context) ([]types.PerfCount
 	return c.api.RetrieveCounters(ctx)
 }
 
+func (c *govmomiClient) RetrievePerfIntervals(ctx context.Context) ([]types.PerfInterval, error) {
+	return c.api.RetrievePerfIntervals(ctx)
+}
+
+// FindAvailableCounters returns keys of perf counters available at real-time interval for hosts and VMs,
+// mapped by entity type ("host" or "vm")
+// Counters are queried for every host and first VM of each host, as available counters depend on ESXi version
+func (c *govmomiClient) FindAvailableCounters(ctx context.Context) (map[string]map[int32]bool, error) {
+	available := map[string]map[int32]bool{"host": map[int32]bool{}, "vm": map[int32]bool{}}
+	add := func(entityType string, ref types.ManagedObjectReference) error {
+		keys, err := c.api.QueryAvailableCounters(ctx, ref)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			available[entityType][key] = true
+		}
+		return nil
+	}
+
+	hosts, err := c.api.RetrieveHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		if err := add("host", host.Reference()); err != nil {
+			return nil, err
+		}
+		vms, err := c.api.RetrieveVMs(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(vms) == 0 {
+			continue
+		}
+		if err := add("vm", vms[0].Reference()); err != nil {
+			return nil, err
+		}
+	}
+	return available, nil
+}
+
 // ClearCache clears cache for all API retrieve operations
 func (c *govmomiClient) ClearCache() {
 	c.api.ClearCache()
 }
 
-// FindHosts returns all hosts for configured cluster
+// SetOptionalProperties sets optional properties retrieved for hosts and VMs, mapped by entity type ("host" or "vm")
+func (c *govmomiClient) SetOptionalProperties(props map[string][]string) {
+	c.api.SetOptionalProperties(props)
+}
+
+// FindHosts returns all hosts for configured clusters
 func (c *govmomiClient) FindHosts(ctx context.Context, hostName string) ([]mo.HostSystem, error) {
 	hosts, err := c.api.RetrieveHosts(ctx)
 	if err != nil {
@@ -126,6 +410,58 @@ func (c *govmomiClient) FindHosts(ctx context.Context, hostName string) ([]mo.Ho
 	return results, nil
 }
 
+// FindClusters returns all configured clusters with given name
+func (c *govmomiClient) FindClusters(ctx context.Context, clusterName string) ([]mo.ClusterComputeResource, error) {
+	clusters, err := c.api.RetrieveClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := []mo.ClusterComputeResource{}
+	for _, cluster := range clusters {
+		if cluster.Name == clusterName || clusterName == "*" {
+			results = append(results, cluster)
+		}
+	}
+	return results, nil
+}
+
+// FindClusterByHost returns configured cluster which given host belongs to, or nil if host does not belong to any of them
+func (c *govmomiClient) FindClusterByHost(ctx context.Context, host mo.HostSystem) (*mo.ClusterComputeResource, error) {
+	if host.Parent == nil {
+		return nil, nil
+	}
+	clusters, err := c.api.RetrieveClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range clusters {
+		if cluster.Reference().Value == host.Parent.Value {
+			return &cluster, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindDatacenterName returns name of datacenter given configured compute resource, host or datastore belongs to
+func (c *govmomiClient) FindDatacenterName(ctx context.Context, ref types.ManagedObjectReference) (string, error) {
+	return c.api.RetrieveDatacenterName(ctx, ref)
+}
+
+// FindDatastores returns all datastores with given name for configured clusters
+func (c *govmomiClient) FindDatastores(ctx context.Context, datastoreName string) ([]mo.Datastore, error) {
+	datastores, err := c.api.RetrieveDatastores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := []mo.Datastore{}
+	for _, ds := range datastores {
+		if ds.Name == datastoreName || datastoreName == "*" {
+			results = append(results, ds)
+		}
+	}
+	return results, nil
+}
+
 // FindVMs retuns all virtual machines for given host
 func (c *govmomiClient) FindVMs(ctx context.Context, host mo.HostSystem, vmName string) ([]mo.VirtualMachine, error) {
 	vms, err := c.api.RetrieveVMs(ctx, host)
@@ -141,6 +477,199 @@ func (c *govmomiClient) FindVMs(ctx context.Context, host mo.HostSystem, vmName
 	return results, nil
 }
 
+// FindResourcePools returns all resource pools with given name for configured clusters
+func (c *govmomiClient) FindResourcePools(ctx context.Context, poolName string) ([]mo.ResourcePool, error) {
+	pools, err := c.api.RetrieveResourcePools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := []mo.ResourcePool{}
+	for _, pool := range pools {
+		if pool.Name == poolName || poolName == "*" {
+			results = append(results, pool)
+		}
+	}
+	return results, nil
+}
+
+// FindResourcePoolVMs returns all virtual machines with given name (can be *) which belong to given resource pool
+func (c *govmomiClient) FindResourcePoolVMs(ctx context.Context, pool mo.ResourcePool, vmName string) ([]mo.VirtualMachine, error) {
+	members := map[string]bool{}
+	for _, ref := range pool.Vm {
+		members[ref.Value] = true
+	}
+	results := []mo.VirtualMachine{}
+	if len(members) == 0 {
+		return results, nil
+	}
+	hosts, err := c.api.RetrieveHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		vms, err := c.FindVMs(ctx, host, vmName)
+		if err != nil {
+			return nil, err
+		}
+		for _, vm := range vms {
+			if members[vm.Reference().Value] {
+				results = append(results, vm)
+			}
+		}
+	}
+	return results, nil
+}
+
+// triggeredAlarm holds state of alarm triggered on cluster entity together with alarm definition
+// and name of datacenter the entity belongs to
+type triggeredAlarm struct {
+	entityType     string
+	entityName     string
+	datacenterName string
+	state          types.AlarmState
+	alarm          mo.Alarm
+}
+
+// FindTriggeredAlarms returns alarms triggered on cluster, hosts, VMs and datastores with given entity type and name (both can be *)
+func (c *govmomiClient) FindTriggeredAlarms(ctx context.Context, entityType string, entityName string) ([]triggeredAlarm, error) {
+	results := []triggeredAlarm{}
+	// Datacenter of VM is looked up by its host, as VMs are not assigned to datacenters directly
+	add := func(typ string, name string, dcRef types.ManagedObjectReference, states []types.AlarmState) error {
+		if (typ != entityType && entityType != "*") || (name != entityName && entityName != "*") || len(states) == 0 {
+			return nil
+		}
+		dcName, err := c.api.RetrieveDatacenterName(ctx, dcRef)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			results = append(results, triggeredAlarm{entityType: typ, entityName: name, datacenterName: dcName, state: state})
+		}
+		return nil
+	}
+
+	if entityType == "cluster" || entityType == "*" {
+		clusters, err := c.api.RetrieveClusters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range clusters {
+			if err := add("cluster", cluster.Name, cluster.Reference(), cluster.TriggeredAlarmState); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if entityType == "host" || entityType == "vm" || entityType == "*" {
+		hosts, err := c.api.RetrieveHosts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			if err := add("host", host.Name, host.Reference(), host.TriggeredAlarmState); err != nil {
+				return nil, err
+			}
+			if entityType == "host" {
+				continue
+			}
+			vms, err := c.api.RetrieveVMs(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			for _, vm := range vms {
+				if err := add("vm", vm.Name, host.Reference(), vm.TriggeredAlarmState); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if entityType == "datastore" || entityType == "*" {
+		datastores, err := c.api.RetrieveDatastores(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, ds := range datastores {
+			if err := add("datastore", ds.Name, ds.Reference(), ds.TriggeredAlarmState); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	// Retrieve definitions (i.e. names) of triggered alarms
+	refs := []types.ManagedObjectReference{}
+	for _, r := range results {
+		refs = append(refs, r.state.Alarm)
+	}
+	alarms, err := c.api.RetrieveAlarms(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		for _, alarm := range alarms {
+			if alarm.Reference().Value == results[i].state.Alarm.Value {
+				results[i].alarm = alarm
+				break
+			}
+		}
+	}
+	return results, nil
+}
+
+// FindEvents returns events of given type (can be *) created since given time, together with time the events were read up to
+// Events related to compute resources other than configured clusters and standalone hosts are skipped
+func (c *govmomiClient) FindEvents(ctx context.Context, since time.Time, eventType string) ([]types.BaseEvent, time.Time, error) {
+	events, until, err := c.api.RetrieveEvents(ctx, since)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	clusters, err := c.api.RetrieveClusters(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	hosts, err := c.api.RetrieveHosts(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// Compute resources of standalone hosts are parents of these hosts
+	computeResources := map[string]bool{}
+	for _, cluster := range clusters {
+		computeResources[cluster.Reference().Value] = true
+	}
+	for _, host := range hosts {
+		if host.Parent != nil {
+			computeResources[host.Parent.Value] = true
+		}
+	}
+
+	results := []types.BaseEvent{}
+	for _, e := range events {
+		if eventTypeName(e) != eventType && eventType != "*" {
+			continue
+		}
+		if cr := e.GetEvent().ComputeResource; cr != nil && !computeResources[cr.ComputeResource.Value] {
+			continue
+		}
+		results = append(results, e)
+	}
+	return results, until, nil
+}
+
+// eventTypeName returns type of given event, i.e. VmPoweredOnEvent
+// For extended events, event type ID is returned instead
+func eventTypeName(e types.BaseEvent) string {
+	switch ev := e.(type) {
+	case *types.EventEx:
+		return ev.EventTypeId
+	case *types.ExtendedEvent:
+		return ev.EventTypeId
+	}
+	return reflect.TypeOf(e).Elem().Name()
+}
+
 // FindCounter returns vSphere counter info by counter name, for example cpu.idle.summation
 func (c *govmomiClient) FindCounter(ctx context.Context, counterFullName string) (*types.PerfCounterInfo, error) {
 	// Retrieve all vCenter metrics
@@ -158,6 +687,24 @@ func (c *govmomiClient) FindCounter(ctx context.Context, counterFullName string)
 	return nil, fmt.Errorf("no vsphere perf counters found for %s", counterFullName)
 }
 
+// FindCounters returns all vSphere counters info matching given group, name and rollup type (each of them can be *)
+func (c *govmomiClient) FindCounters(ctx context.Context, group, name, rollup string) ([]types.PerfCounterInfo, error) {
+	// Retrieve all vCenter metrics
+	vMetrics, err := c.api.RetrieveCounters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := []types.PerfCounterInfo{}
+	for _, pc := range vMetrics {
+		if (pc.GroupInfo.GetElementDescription().Key == group || group == "*") &&
+			(pc.NameInfo.GetElementDescription().Key == name || name == "*") &&
+			(fmt.Sprint(pc.RollupType) == rollup || rollup == "*") {
+			results = append(results, pc)
+		}
+	}
+	return results, nil
+}
+
 // FindCounterByKey returns vSphere counter info by counter key (ID)
 func (c *govmomiClient) FindCounterByKey(ctx context.Context, key int32) (*types.PerfCounterInfo, error) {
 	// Retrieve all vCenter metrics
@@ -174,6 +721,21 @@ func (c *govmomiClient) FindCounterByKey(ctx context.Context, key int32) (*types
 	return nil, fmt.Errorf("no vsphere perf counters found for key %d", key)
 }
 
+// FindClusterByRef returns mo.ClusterComputeResource for given reference
+func (c *govmomiClient) FindClusterByRef(ctx context.Context, ref types.ManagedObjectReference) (*mo.ClusterComputeResource, error) {
+	clusters, err := c.api.RetrieveClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range clusters {
+		if cluster.Reference().Value == ref.Value {
+			return &cluster, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot find cluster by reference %s", ref.Value)
+}
+
+// FindDatastoreByRef returns mo.Datastore for given reference
 func (c *govmomiClient) FindDatastoreByRef(ctx context.Context, ref types.ManagedObjectReference) (*mo.Datastore, error) {
 	datastores, err := c.api.RetrieveDatastores(ctx)
 	if err != nil {
@@ -184,7 +746,41 @@ func (c *govmomiClient) FindDatastoreByRef(ctx context.Context, ref types.Manage
 			return &ds, nil
 		}
 	}
-	return nil, fmt.Errorf("cannot find dataqstore by reference %s", ref.Value)
+	return nil, fmt.Errorf("cannot find datastore by reference %s", ref.Value)
+}
+
+// FindDatastoreHosts returns configured hosts which have given datastore mounted and accessible
+func (c *govmomiClient) FindDatastoreHosts(ctx context.Context, ds mo.Datastore) ([]mo.HostSystem, error) {
+	hosts, err := c.api.RetrieveHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := []mo.HostSystem{}
+	for _, host := range hosts {
+		for _, mount := range ds.Host {
+			if mount.Key.Value != host.Reference().Value {
+				continue
+			}
+			if mount.MountInfo.Accessible != nil && !*mount.MountInfo.Accessible {
+				continue
+			}
+			results = append(results, host)
+		}
+	}
+	return results, nil
+}
+
+// FindResourcePoolNames returns names of all resource pools, mapped by resource pool reference value
+func (c *govmomiClient) FindResourcePoolNames(ctx context.Context) (map[string]string, error) {
+	pools, err := c.api.RetrieveResourcePools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, pool := range pools {
+		names[pool.Reference().Value] = pool.Name
+	}
+	return names, nil
 }
 
 // FindHostByRef returns mo.HostSystem for given reference
@@ -224,12 +820,13 @@ func (c *govmomiClient) FindVMByRef(ctx context.Context, ref types.ManagedObject
 
 // GetInstances extracts instance list from provided metric
 // Each metric contains multiple instances, for example disk-related metrics returns 1 instance for each disk
+// Entity may contain no instances if requested counters are not available for it (i.e. raw counters requested with wildcard)
 func (c *govmomiClient) GetInstances(metric types.BasePerfEntityMetricBase) ([]types.BasePerfMetricSeries, error) {
-	result := metric.(*types.PerfEntityMetric).Value
-	if len(result) == 0 {
-		return nil, fmt.Errorf("No instances found for specified metric")
+	entityMetric, ok := metric.(*types.PerfEntityMetric)
+	if !ok {
+		return nil, fmt.Errorf("unexpected perf entity metric type %T", metric)
 	}
-	return result, nil
+	return entityMetric.Value, nil
 }
 
 // GetInstanceSeries retrieves metric series for given instance
@@ -0,0 +1,413 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+const unitMegahertz = 1000 * 1000
+
+// vmInstancePropertyFunc calculates metric values for each instance (i.e. guest filesystem) from virtual machine properties
+type vmInstancePropertyFunc func(vm mo.VirtualMachine) map[string]interface{}
+
+// hostPropertyFunc calculates metric value from host properties
+// It returns nil if property needed to calculate metric is not available
+type hostPropertyFunc func(host mo.HostSystem) interface{}
+
+// vmPropertyFunc calculates metric value from virtual machine properties
+// It returns nil if property needed to calculate metric is not available
+type vmPropertyFunc func(vm mo.VirtualMachine) interface{}
+
+// Numeric codes of vSphere states, reported by property metrics
+var (
+	vmPowerStateCodes = map[string]int{
+		"poweredOff": 0,
+		"poweredOn":  1,
+		"suspended":  2,
+	}
+
+	hostPowerStateCodes = map[string]int{
+		"poweredOff": 0,
+		"poweredOn":  1,
+		"standBy":    2,
+		"unknown":    3,
+	}
+
+	hostConnectionStateCodes = map[string]int{
+		"connected":     0,
+		"disconnected":  1,
+		"notResponding": 2,
+	}
+
+	vmConnectionStateCodes = map[string]int{
+		"connected":    0,
+		"disconnected": 1,
+		"orphaned":     2,
+		"inaccessible": 3,
+		"invalid":      4,
+	}
+
+	overallStatusCodes = map[string]int{
+		"gray":   0,
+		"green":  1,
+		"yellow": 2,
+		"red":    3,
+	}
+
+	healthStateCodes = map[string]int{
+		"unknown": 0,
+		"green":   1,
+		"yellow":  2,
+		"red":     3,
+	}
+
+	toolsVersionStatusCodes = map[string]int{
+		"guestToolsCurrent":      0,
+		"guestToolsSupportedNew": 0,
+		"guestToolsSupportedOld": 1,
+		"guestToolsNeedUpgrade":  1,
+		"guestToolsTooOld":       2,
+		"guestToolsTooNew":       2,
+		"guestToolsBlacklisted":  2,
+		"guestToolsNotInstalled": 3,
+		"guestToolsUnmanaged":    4,
+	}
+)
+
+// Optional host properties, retrieved only when metrics of given host metric group are requested
+var hostGroupProperties = map[string][]string{
+	"mem":     []string{"hardware"},
+	"summary": []string{"hardware"},
+	"sensor":  []string{"runtime.healthSystemRuntime"},
+}
+
+// Optional VM properties, retrieved only when metrics of given VM metric group are requested
+var vmGroupProperties = map[string][]string{
+	"snapshot":  []string{"snapshot", "layoutEx"},
+	"guestDisk": []string{"guest.disk"},
+}
+
+// VM property metric map
+// Maps Snap metrics to functions calculating metric value from VM properties, instead of perf counters
+var vmPropertyMetrics = map[string]map[string]vmPropertyFunc{
+	"summary": map[string]vmPropertyFunc{
+		"powerState": func(vm mo.VirtualMachine) interface{} {
+			return stateCode(vmPowerStateCodes, string(vm.Summary.Runtime.PowerState))
+		},
+		"connectionState": func(vm mo.VirtualMachine) interface{} {
+			return stateCode(vmConnectionStateCodes, string(vm.Summary.Runtime.ConnectionState))
+		},
+		"overallStatus": func(vm mo.VirtualMachine) interface{} {
+			return stateCode(overallStatusCodes, string(vm.Summary.OverallStatus))
+		},
+		"numCpu": func(vm mo.VirtualMachine) interface{} {
+			return vm.Summary.Config.NumCpu
+		},
+		"memorySize": func(vm mo.VirtualMachine) interface{} {
+			return vm.Summary.Config.MemorySizeMB
+		},
+		"uptime": func(vm mo.VirtualMachine) interface{} {
+			return vm.Summary.QuickStats.UptimeSeconds
+		},
+		"toolsRunning": func(vm mo.VirtualMachine) interface{} {
+			if vm.Summary.Guest == nil {
+				return nil
+			}
+			if vm.Summary.Guest.ToolsRunningStatus == "guestToolsRunning" {
+				return 1
+			}
+			return 0
+		},
+		"toolsVersionStatus": func(vm mo.VirtualMachine) interface{} {
+			if vm.Summary.Guest == nil {
+				return nil
+			}
+			return stateCode(toolsVersionStatusCodes, vm.Summary.Guest.ToolsVersionStatus2)
+		},
+	},
+	"snapshot": map[string]vmPropertyFunc{
+		"count": func(vm mo.VirtualMachine) interface{} {
+			if vm.Snapshot == nil {
+				return 0
+			}
+			count := 0
+			walkSnapshots(vm.Snapshot.RootSnapshotList, func(types.VirtualMachineSnapshotTree) {
+				count++
+			})
+			return count
+		},
+		"oldestAge": func(vm mo.VirtualMachine) interface{} {
+			if vm.Snapshot == nil {
+				return 0
+			}
+			oldest := time.Now()
+			walkSnapshots(vm.Snapshot.RootSnapshotList, func(snapshot types.VirtualMachineSnapshotTree) {
+				if snapshot.CreateTime.Before(oldest) {
+					oldest = snapshot.CreateTime
+				}
+			})
+			return int64(time.Since(oldest).Seconds())
+		},
+		"size": func(vm mo.VirtualMachine) interface{} {
+			if vm.LayoutEx == nil {
+				return nil
+			}
+			return snapshotSize(vm.LayoutEx) / unitMegabyte
+		},
+		"consolidationNeeded": func(vm mo.VirtualMachine) interface{} {
+			if vm.Summary.Runtime.ConsolidationNeeded == nil {
+				return nil
+			}
+			if *vm.Summary.Runtime.ConsolidationNeeded {
+				return 1
+			}
+			return 0
+		},
+	},
+}
+
+// VM instance property metric map
+// Maps Snap metrics to functions calculating per-instance metric values from VM properties
+var vmInstancePropertyMetrics = map[string]map[string]vmInstancePropertyFunc{
+	"guestDisk": map[string]vmInstancePropertyFunc{
+		"capacity": func(vm mo.VirtualMachine) map[string]interface{} {
+			values := map[string]interface{}{}
+			for _, disk := range guestDisks(vm) {
+				values[disk.DiskPath] = disk.Capacity / unitMegabyte
+			}
+			return values
+		},
+		"free": func(vm mo.VirtualMachine) map[string]interface{} {
+			values := map[string]interface{}{}
+			for _, disk := range guestDisks(vm) {
+				values[disk.DiskPath] = disk.FreeSpace / unitMegabyte
+			}
+			return values
+		},
+		"usage": func(vm mo.VirtualMachine) map[string]interface{} {
+			values := map[string]interface{}{}
+			for _, disk := range guestDisks(vm) {
+				if disk.Capacity == 0 {
+					continue
+				}
+				values[disk.DiskPath] = float64(disk.Capacity-disk.FreeSpace) / float64(disk.Capacity) * 100
+			}
+			return values
+		},
+	},
+}
+
+// Host property metric map
+// Maps Snap metrics to functions calculating metric value from host properties, instead of perf counters
+var hostPropertyMetrics = map[string]map[string]hostPropertyFunc{
+	"summary": map[string]hostPropertyFunc{
+		"cpuModel": func(host mo.HostSystem) interface{} {
+			if host.Hardware == nil || len(host.Hardware.CpuPkg) == 0 {
+				return nil
+			}
+			return host.Hardware.CpuPkg[0].Description
+		},
+		"cpuMhz": func(host mo.HostSystem) interface{} {
+			if host.Hardware == nil {
+				return nil
+			}
+			return host.Hardware.CpuInfo.Hz / unitMegahertz
+		},
+		"cpuSockets": func(host mo.HostSystem) interface{} {
+			if host.Hardware == nil {
+				return nil
+			}
+			return host.Hardware.CpuInfo.NumCpuPackages
+		},
+		"cpuCores": func(host mo.HostSystem) interface{} {
+			if host.Hardware == nil {
+				return nil
+			}
+			return host.Hardware.CpuInfo.NumCpuCores
+		},
+		"cpuThreads": func(host mo.HostSystem) interface{} {
+			if host.Hardware == nil {
+				return nil
+			}
+			return host.Hardware.CpuInfo.NumCpuThreads
+		},
+		"cpuCapacity": func(host mo.HostSystem) interface{} {
+			if host.Hardware == nil {
+				return nil
+			}
+			return host.Hardware.CpuInfo.Hz / unitMegahertz * int64(host.Hardware.CpuInfo.NumCpuCores)
+		},
+		"connectionState": func(host mo.HostSystem) interface{} {
+			if host.Summary.Runtime == nil {
+				return nil
+			}
+			return stateCode(hostConnectionStateCodes, string(host.Summary.Runtime.ConnectionState))
+		},
+		"powerState": func(host mo.HostSystem) interface{} {
+			if host.Summary.Runtime == nil {
+				return nil
+			}
+			return stateCode(hostPowerStateCodes, string(host.Summary.Runtime.PowerState))
+		},
+		"maintenanceMode": func(host mo.HostSystem) interface{} {
+			if host.Summary.Runtime == nil {
+				return nil
+			}
+			if host.Summary.Runtime.InMaintenanceMode {
+				return 1
+			}
+			return 0
+		},
+		"bootTime": func(host mo.HostSystem) interface{} {
+			if host.Summary.Runtime == nil || host.Summary.Runtime.BootTime == nil {
+				return nil
+			}
+			return host.Summary.Runtime.BootTime.Unix()
+		},
+		"uptime": func(host mo.HostSystem) interface{} {
+			return host.Summary.QuickStats.Uptime
+		},
+		"overallStatus": func(host mo.HostSystem) interface{} {
+			return stateCode(overallStatusCodes, string(host.Summary.OverallStatus))
+		},
+	},
+}
+
+// guestDisks returns guest filesystems reported by VMware Tools
+func guestDisks(vm mo.VirtualMachine) []types.GuestDiskInfo {
+	if vm.Guest == nil {
+		return nil
+	}
+	return vm.Guest.Disk
+}
+
+// walkSnapshots calls given function for each snapshot in snapshot tree
+func walkSnapshots(snapshots []types.VirtualMachineSnapshotTree, f func(types.VirtualMachineSnapshotTree)) {
+	for _, snapshot := range snapshots {
+		f(snapshot)
+		walkSnapshots(snapshot.ChildSnapshotList, f)
+	}
+}
+
+// snapshotSize returns total size in bytes of VM snapshot files, including delta disks
+// Delta disks are all units of disk chain except the first one, which is the base disk
+func snapshotSize(layout *types.VirtualMachineFileLayoutEx) int64 {
+	fileKeys := map[int32]bool{}
+	for _, disk := range layout.Disk {
+		for i, unit := range disk.Chain {
+			if i == 0 {
+				continue
+			}
+			for _, key := range unit.FileKey {
+				fileKeys[key] = true
+			}
+		}
+	}
+
+	size := int64(0)
+	for _, file := range layout.File {
+		switch file.Type {
+		case "snapshotData", "snapshotMemory", "snapshotList":
+			size += file.Size
+		default:
+			if fileKeys[file.Key] {
+				size += file.Size
+			}
+		}
+	}
+	return size
+}
+
+// hostSensor holds reading and health state of host hardware sensor or hardware element
+type hostSensor struct {
+	name        string
+	sensorType  string
+	unit        string
+	healthState string
+	reading     *float64 // Available for numeric sensors only
+}
+
+// hostSensors returns numeric sensors and hardware elements (CPU, memory, storage) reported by host health system
+func hostSensors(host mo.HostSystem) []hostSensor {
+	health := host.Runtime.HealthSystemRuntime
+	if health == nil {
+		return nil
+	}
+
+	sensors := []hostSensor{}
+	if health.SystemHealthInfo != nil {
+		for _, info := range health.SystemHealthInfo.NumericSensorInfo {
+			reading := float64(info.CurrentReading) * math.Pow10(int(info.UnitModifier))
+			unit := info.BaseUnits
+			if info.RateUnits != "" {
+				unit += "/" + info.RateUnits
+			}
+			sensors = append(sensors, hostSensor{
+				name:        info.Name,
+				sensorType:  info.SensorType,
+				unit:        unit,
+				healthState: elementKey(info.HealthState),
+				reading:     &reading,
+			})
+		}
+	}
+	if health.HardwareStatusInfo != nil {
+		elements := map[string][]types.BaseHostHardwareElementInfo{
+			"memory": health.HardwareStatusInfo.MemoryStatusInfo,
+			"cpu":    health.HardwareStatusInfo.CpuStatusInfo,
+		}
+		for i := range health.HardwareStatusInfo.StorageStatusInfo {
+			elements["storage"] = append(elements["storage"], &health.HardwareStatusInfo.StorageStatusInfo[i])
+		}
+		for _, sensorType := range []string{"cpu", "memory", "storage"} {
+			for _, element := range elements[sensorType] {
+				info := element.GetHostHardwareElementInfo()
+				sensors = append(sensors, hostSensor{
+					name:        info.Name,
+					sensorType:  sensorType,
+					healthState: elementKey(info.Status),
+				})
+			}
+		}
+	}
+	return sensors
+}
+
+// elementKey returns lowercase key of given element description, i.e. health state
+func elementKey(desc types.BaseElementDescription) string {
+	if desc == nil {
+		return ""
+	}
+	return strings.ToLower(desc.GetElementDescription().Key)
+}
+
+// stateCode returns numeric code for given vSphere state, or nil if state is unknown
+func stateCode(codes map[string]int, state string) interface{} {
+	if code, ok := codes[state]; ok {
+		return code
+	}
+	return nil
+}
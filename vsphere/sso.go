@@ -0,0 +1,363 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	vimxml "github.com/vmware/govmomi/vim25/xml"
+)
+
+const (
+	// Authentication modes selected by authMode config
+	authModePassword = "password"
+	authModeToken    = "token"
+
+	// Path of vCenter SSO Security Token Service for default SSO domain (tenant), used if ssoURL is not configured
+	defaultSTSPath = "/sts/STSService/vsphere.local"
+
+	// Requested lifetime of SSO token, STS may issue token with shorter lifetime
+	tokenLifetime = time.Hour
+
+	// Time before token expiry when session is considered expired, so new token is issued in time
+	tokenRenewMargin = 5 * time.Minute
+
+	// Time format of WS-Security timestamps
+	wsuTimeFormat = "2006-01-02T15:04:05.000Z"
+)
+
+// ssoToken is SAML token issued by vCenter SSO
+// Bearer token is used by presenting it, holder-of-key token is bound to client certificate
+// and requests using it have to be signed with certificate's private key
+type ssoToken struct {
+	// Raw XML of SAML assertion
+	assertion string
+	expires   time.Time
+
+	// Signer of requests using holder-of-key token, nil for bearer token
+	signer *tokenSigner
+}
+
+// XML namespaces used in WS-Trust and WS-Security requests
+const (
+	nsSOAP  = "http://schemas.xmlsoap.org/soap/envelope/"
+	nsWSSE  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	nsWSSE1 = "http://docs.oasis-open.org/wss/oasis-wss-wssecurity-secext-1.1.xsd"
+	nsWSU   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+	nsWST   = "http://docs.oasis-open.org/ws-sx/ws-trust/200512"
+	nsDS    = "http://www.w3.org/2000/09/xmldsig#"
+
+	// Token key types requested from STS
+	keyTypeBearer    = "http://docs.oasis-open.org/ws-sx/ws-trust/200512/Bearer"
+	keyTypePublicKey = "http://docs.oasis-open.org/ws-sx/ws-trust/200512/PublicKey"
+
+	// IDs of signed request elements and client certificate token
+	bodyID        = "_body"
+	timestampID   = "_timestamp"
+	certificateID = "_certificate"
+)
+
+// Signed elements (SOAP body and WS-Security timestamp) are written directly in exclusive canonical XML form
+// (namespaces used by element declared on it, attributes in canonical order, no empty element tags),
+// so their digests can be calculated from the same text which is sent
+
+// timestampElement is WS-Security timestamp in canonical form
+const timestampElement = `<wsu:Timestamp xmlns:wsu="` + nsWSU + `" wsu:Id="` + timestampID + `"><wsu:Created>%s</wsu:Created><wsu:Expires>%s</wsu:Expires></wsu:Timestamp>`
+
+// issueTokenBody is WS-Trust request for SAML token in canonical form, key type is either bearer or public key
+// (holder-of-key), in which case UseKey points to client certificate the token is bound to
+const issueTokenBody = `<soapenv:Body xmlns:soapenv="` + nsSOAP + `" xmlns:wsu="` + nsWSU + `" wsu:Id="` + bodyID + `">` +
+	`<wst:RequestSecurityToken xmlns:wst="` + nsWST + `">` +
+	`<wst:TokenType>urn:oasis:names:tc:SAML:2.0:assertion</wst:TokenType>` +
+	`<wst:RequestType>http://docs.oasis-open.org/ws-sx/ws-trust/200512/Issue</wst:RequestType>` +
+	`<wst:Lifetime><wsu:Created>%s</wsu:Created><wsu:Expires>%s</wsu:Expires></wst:Lifetime>` +
+	`<wst:Renewing Allow="false" OK="false"></wst:Renewing>` +
+	`<wst:Delegatable>false</wst:Delegatable>` +
+	`<wst:KeyType>%s</wst:KeyType>` +
+	`<wst:SignatureAlgorithm>http://www.w3.org/2001/04/xmldsig-more#rsa-sha256</wst:SignatureAlgorithm>` +
+	`%s` +
+	`</wst:RequestSecurityToken>` +
+	`</soapenv:Body>`
+
+// useKeyElement binds requested holder-of-key token to client certificate
+const useKeyElement = `<wst:UseKey Sig="` + certificateID + `"></wst:UseKey>`
+
+// loginByTokenBody is LoginByToken call in canonical form
+const loginByTokenBody = `<soapenv:Body xmlns:soapenv="` + nsSOAP + `" xmlns:wsu="` + nsWSU + `" wsu:Id="` + bodyID + `">` +
+	`<LoginByToken xmlns="urn:vim25"><_this type="SessionManager">%s</_this></LoginByToken>` +
+	`</soapenv:Body>`
+
+// securityRequest is SOAP envelope with WS-Security header
+const securityRequest = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="` + nsSOAP + `" xmlns:wsse="` + nsWSSE + `" xmlns:wsu="` + nsWSU + `">
+<soapenv:Header>
+<wsse:Security>
+%s
+</wsse:Security>
+</soapenv:Header>
+%s
+</soapenv:Envelope>`
+
+// usernameTokenElement authenticates token request with username and password
+const usernameTokenElement = `<wsse:UsernameToken><wsse:Username>%s</wsse:Username><wsse:Password>%s</wsse:Password></wsse:UsernameToken>`
+
+// certificateTokenElement contains client certificate which holder-of-key token request is signed with
+const certificateTokenElement = `<wsse:BinarySecurityToken EncodingType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary" ValueType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-x509-token-profile-1.0#X509v3" wsu:Id="` + certificateID + `">%s</wsse:BinarySecurityToken>`
+
+// certificateKeyInfo references client certificate as the key request is signed with
+const certificateKeyInfo = `<wsse:SecurityTokenReference><wsse:Reference URI="#` + certificateID + `" ValueType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-x509-token-profile-1.0#X509v3"></wsse:Reference></wsse:SecurityTokenReference>`
+
+// assertionKeyInfo references holder-of-key SAML assertion by its ID as the key request is signed with
+const assertionKeyInfo = `<wsse:SecurityTokenReference xmlns:wsse11="` + nsWSSE1 + `" wsse11:TokenType="http://docs.oasis-open.org/wss/oasis-wss-saml-token-profile-1.1#SAMLV2.0"><wsse:KeyIdentifier ValueType="http://docs.oasis-open.org/wss/oasis-wss-saml-token-profile-1.1#SAMLID">%s</wsse:KeyIdentifier></wsse:SecurityTokenReference>`
+
+// signedInfoElement lists digests of signed elements in canonical form, its canonical text is signed with RSA-SHA256
+const signedInfoElement = `<ds:SignedInfo xmlns:ds="` + nsDS + `">` +
+	`<ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></ds:CanonicalizationMethod>` +
+	`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"></ds:SignatureMethod>` +
+	`%s` +
+	`</ds:SignedInfo>`
+
+// referenceElement is digest of one signed element
+const referenceElement = `<ds:Reference URI="#%s">` +
+	`<ds:Transforms><ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></ds:Transform></ds:Transforms>` +
+	`<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></ds:DigestMethod>` +
+	`<ds:DigestValue>%s</ds:DigestValue>` +
+	`</ds:Reference>`
+
+// signatureElement is XML signature of request
+const signatureElement = `<ds:Signature xmlns:ds="` + nsDS + `">%s<ds:SignatureValue>%s</ds:SignatureValue><ds:KeyInfo>%s</ds:KeyInfo></ds:Signature>`
+
+// issueTokenResponse is part of WS-Trust response containing issued token, or SOAP fault
+type issueTokenResponse struct {
+	Body struct {
+		Fault *struct {
+			String string `xml:"faultstring"`
+		} `xml:"Fault"`
+		Response struct {
+			Lifetime struct {
+				Expires string `xml:"Expires"`
+			} `xml:"Lifetime"`
+			Token struct {
+				Assertion string `xml:",innerxml"`
+			} `xml:"RequestedSecurityToken"`
+		} `xml:"RequestSecurityTokenResponseCollection>RequestSecurityTokenResponse"`
+	} `xml:"Body"`
+}
+
+// tokenSigner signs WS-Security requests with private key of client certificate, used with holder-of-key tokens
+type tokenSigner struct {
+	certificate []byte
+	key         *rsa.PrivateKey
+}
+
+// newTokenSigner loads client certificate and its RSA private key from given PEM files
+func newTokenSigner(certFile, keyFile string) (*tokenSigner, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load client certificate: %v", err)
+	}
+	key, ok := cert.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("holder-of-key token requires RSA private key")
+	}
+	return &tokenSigner{certificate: cert.Certificate[0], key: key}, nil
+}
+
+// signedElement is element of request referenced by signature, in canonical form
+type signedElement struct {
+	id        string
+	canonical string
+}
+
+// sign returns XML signature of given elements, with given key info identifying the signing key
+func (s *tokenSigner) sign(elements []signedElement, keyInfo string) (string, error) {
+	references := ""
+	for _, e := range elements {
+		digest := sha256.Sum256([]byte(e.canonical))
+		references += fmt.Sprintf(referenceElement, e.id, base64.StdEncoding.EncodeToString(digest[:]))
+	}
+	signedInfo := fmt.Sprintf(signedInfoElement, references)
+
+	digest := sha256.Sum256([]byte(signedInfo))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(signatureElement, signedInfo, base64.StdEncoding.EncodeToString(signature), keyInfo), nil
+}
+
+// assertionID returns ID of given SAML assertion, used to reference holder-of-key token in signature
+func assertionID(assertion string) (string, error) {
+	var parsed struct {
+		ID string `xml:"ID,attr"`
+	}
+	if err := xml.Unmarshal([]byte(assertion), &parsed); err != nil {
+		return "", fmt.Errorf("unable to parse SAML assertion: %v", err)
+	}
+	if parsed.ID == "" {
+		return "", fmt.Errorf("SAML assertion has no ID")
+	}
+	return parsed.ID, nil
+}
+
+// stsURL returns URL of SSO Security Token Service, derived from vCenter URL if not configured
+func stsURL(vURL *url.URL, ssoURL string) string {
+	if ssoURL != "" {
+		return ssoURL
+	}
+	u := *vURL
+	u.User = nil
+	u.Path = defaultSTSPath
+	u.RawQuery = ""
+	return u.String()
+}
+
+// escapeXML escapes text to be placed in XML request
+func escapeXML(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// canonicalTextEscaper escapes text of signed elements the way exclusive XML canonicalization does
+var canonicalTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+// postSOAP sends given SOAP request and returns response body
+func postSOAP(ctx context.Context, client *http.Client, url, action, body string) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", action)
+	return client.Do(req.WithContext(ctx))
+}
+
+// issueToken requests SAML token from SSO Security Token Service, authenticating with username and password
+// If signer is given, holder-of-key token bound to signer's certificate is requested with signed request,
+// otherwise bearer token is requested
+func issueToken(ctx context.Context, client *http.Client, sts, username, password string, signer *tokenSigner) (*ssoToken, error) {
+	now := time.Now().UTC()
+	timestamp := fmt.Sprintf(timestampElement, now.Format(wsuTimeFormat), now.Add(tokenRenewMargin).Format(wsuTimeFormat))
+	header := timestamp + fmt.Sprintf(usernameTokenElement, escapeXML(username), escapeXML(password))
+
+	keyType, useKey := keyTypeBearer, ""
+	if signer != nil {
+		keyType, useKey = keyTypePublicKey, useKeyElement
+	}
+	body := fmt.Sprintf(issueTokenBody, now.Format(wsuTimeFormat), now.Add(tokenLifetime).Format(wsuTimeFormat), keyType, useKey)
+
+	if signer != nil {
+		signature, err := signer.sign([]signedElement{{bodyID, body}, {timestampID, timestamp}}, certificateKeyInfo)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sign token request: %v", err)
+		}
+		header += fmt.Sprintf(certificateTokenElement, base64.StdEncoding.EncodeToString(signer.certificate)) + signature
+	}
+
+	res, err := postSOAP(ctx, client, sts, "http://docs.oasis-open.org/ws-sx/ws-trust/200512/RST/Issue", fmt.Sprintf(securityRequest, header, body))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	content, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response issueTokenResponse
+	if err := xml.Unmarshal(content, &response); err != nil {
+		return nil, fmt.Errorf("unable to parse STS response (%s): %v", res.Status, err)
+	}
+	if response.Body.Fault != nil {
+		return nil, fmt.Errorf("STS fault: %s", response.Body.Fault.String)
+	}
+	if response.Body.Response.Token.Assertion == "" {
+		return nil, fmt.Errorf("STS response does not contain token (%s)", res.Status)
+	}
+
+	expires, err := time.Parse(time.RFC3339, response.Body.Response.Lifetime.Expires)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token expiry time: %v", err)
+	}
+
+	return &ssoToken{assertion: response.Body.Response.Token.Assertion, expires: expires, signer: signer}, nil
+}
+
+// loginByToken logs in to vCenter with SAML token
+// Request using holder-of-key token is signed with private key of certificate the token is bound to
+// Session cookie is stored in SOAP client, so following API calls are authenticated
+func loginByToken(ctx context.Context, c *soap.Client, sessionManager types.ManagedObjectReference, token *ssoToken) error {
+	now := time.Now().UTC()
+	timestamp := fmt.Sprintf(timestampElement, now.Format(wsuTimeFormat), now.Add(tokenRenewMargin).Format(wsuTimeFormat))
+	header := timestamp + token.assertion
+	body := fmt.Sprintf(loginByTokenBody, canonicalTextEscaper.Replace(sessionManager.Value))
+
+	if token.signer != nil {
+		id, err := assertionID(token.assertion)
+		if err != nil {
+			return err
+		}
+		signature, err := token.signer.sign([]signedElement{{bodyID, body}, {timestampID, timestamp}}, fmt.Sprintf(assertionKeyInfo, escapeXML(id)))
+		if err != nil {
+			return fmt.Errorf("unable to sign LoginByToken request: %v", err)
+		}
+		header += signature
+	}
+
+	res, err := postSOAP(ctx, &c.Client, c.URL().String(), fmt.Sprintf("%s/%s", c.Namespace, c.Version), fmt.Sprintf(securityRequest, header, body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody := &methods.LoginByTokenBody{}
+	dec := vimxml.NewDecoder(res.Body)
+	dec.TypeFunc = types.TypeFunc()
+	if err := dec.Decode(&soap.Envelope{Body: resBody}); err != nil {
+		return fmt.Errorf("unable to parse LoginByToken response (%s): %v", res.Status, err)
+	}
+	if f := resBody.Fault(); f != nil {
+		return soap.WrapSoapFault(f)
+	}
+	if resBody.Res == nil {
+		return fmt.Errorf("empty LoginByToken response (%s)", res.Status)
+	}
+
+	return nil
+}
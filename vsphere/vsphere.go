@@ -22,8 +22,13 @@ package vsphere
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -46,6 +51,49 @@ const (
 	nsVMInstance = 8
 	nsVMMetric   = 9
 
+	// Raw perf counter namespace entries, relative to counter group entry
+	counterNs         = "counter"
+	nsCounterName     = 1
+	nsCounterRollup   = 2
+	nsCounterInstance = 3
+
+	nsHostCounterGroup = 6
+	nsVMCounterGroup   = 8
+
+	nsDatastore         = 4
+	nsDatastoreInstance = 5
+	nsDatastoreMetric   = 6
+
+	nsCluster         = 4
+	nsClusterGroup    = 5
+	nsClusterInstance = 6
+	nsClusterMetric   = 7
+
+	nsResourcePool         = 4
+	nsResourcePoolGroup    = 5
+	nsResourcePoolInstance = 6
+	nsResourcePoolMetric   = 7
+
+	nsAlarmEntityType = 4
+	nsAlarmEntity     = 5
+	nsAlarm           = 6
+	nsAlarmMetric     = 7
+
+	nsEventType     = 4
+	nsEventInstance = 5
+	nsEventMetric   = 6
+
+	tagResourcePool   = "resource_pool"
+	tagClusterName    = "cluster_name"
+	tagDatacenterName = "datacenter_name"
+	tagAlarmName      = "alarm_name"
+	tagMessage        = "message"
+	tagUserName       = "user_name"
+	tagHostName       = "hostname"
+	tagVMName         = "vmname"
+	tagSensorType     = "sensor_type"
+	tagUnit           = "unit"
+
 	unitKilobyte = 1024
 	unitMegabyte = unitKilobyte * 1024
 )
@@ -56,23 +104,50 @@ GovomiResources contains methods to retrieve data from vSphereAPI
 */
 type Collector struct {
 	GovmomiResources *govmomiClient
+
+	// Position of events read by previous collection, mapped by event cursor
+	eventCursors map[eventCursor]eventCursorState
+
+	// Error which caused static metric catalog to be returned by last GetMetricTypes call
+	catalogErr error
+
+	// Serializes collections and catalog requests, as they use session selected by Init and event cursors
+	mu sync.Mutex
+}
+
+// eventCursor identifies stream of events read by collections of the same event metrics from the same vCenter,
+// so tasks collecting events with different intervals do not split events between them
+// Cursor is keyed on vCenter URL and collected scope only, so credentials are not kept in it
+type eventCursor struct {
+	url                string
+	datacenterName     string
+	clusterName        string
+	standaloneHostName string
+	metrics            string
+}
+
+// eventCursorState holds vCenter time events were read up to and local time cursor was last used
+type eventCursorState struct {
+	until    time.Time
+	lastUsed time.Time
 }
 
 type parsedQueryResponse struct {
 	hostName        string
 	vmName          string
+	entityRef       types.ManagedObjectReference // Queried entity, names are not unique across clusters and datacenters
 	counterFullName string
 	instance        string
 	data            int64
 }
 
-// perfQuerySpecMap holds map of [entity name]types.PerfQuerySpec
+// perfQuerySpecMap holds map of [entity reference value]types.PerfQuerySpec
 type perfQuerySpecMap map[string]types.PerfQuerySpec
 
-// Metric dependency map
+// Host metric dependency map
 // Maps Snap metrics to vSphere perf counters needed to calculate desired metric
 // Each Snap metric can contain multiple dependencies
-var metricDepMap = map[string]map[string][]string{
+var hostMetricDepMap = map[string]map[string][]string{
 	"cpu": map[string][]string{
 		"idle": []string{"cpu.usage.average"},
 		"wait": []string{"cpu.latency.average"},
@@ -89,6 +164,80 @@ var metricDepMap = map[string]map[string][]string{
 		"packetsTx": []string{"net.packetsTx.summation"},
 		"packetsRx": []string{"net.packetsRx.summation"},
 	},
+	"disk": map[string][]string{
+		"readThroughput":  []string{"disk.read.average"},
+		"writeThroughput": []string{"disk.write.average"},
+		"readIops":        []string{"disk.numberReadAveraged.average"},
+		"writeIops":       []string{"disk.numberWriteAveraged.average"},
+		"deviceLatency":   []string{"disk.deviceLatency.average"},
+		"kernelLatency":   []string{"disk.kernelLatency.average"},
+		"queueLatency":    []string{"disk.queueLatency.average"},
+		"totalLatency":    []string{"disk.totalLatency.average"},
+		"commandsAborted": []string{"disk.commandsAborted.summation"},
+		"busResets":       []string{"disk.busResets.summation"},
+	},
+	"storageAdapter": map[string][]string{
+		"readThroughput":  []string{"storageAdapter.read.average"},
+		"writeThroughput": []string{"storageAdapter.write.average"},
+		"readIops":        []string{"storageAdapter.numberReadAveraged.average"},
+		"writeIops":       []string{"storageAdapter.numberWriteAveraged.average"},
+		"readLatency":     []string{"storageAdapter.totalReadLatency.average"},
+		"writeLatency":    []string{"storageAdapter.totalWriteLatency.average"},
+	},
+	"storagePath": map[string][]string{
+		"readThroughput":  []string{"storagePath.read.average"},
+		"writeThroughput": []string{"storagePath.write.average"},
+		"readIops":        []string{"storagePath.numberReadAveraged.average"},
+		"writeIops":       []string{"storagePath.numberWriteAveraged.average"},
+		"readLatency":     []string{"storagePath.totalReadLatency.average"},
+		"writeLatency":    []string{"storagePath.totalWriteLatency.average"},
+	},
+	"power": map[string][]string{
+		"power":    []string{"power.power.average"},
+		"powerCap": []string{"power.powerCap.average"},
+		"energy":   []string{"power.energy.summation"},
+	},
+}
+
+// Virtual machine metric dependency map
+// Metric groups are separated from host ones, as the same metric name can be calculated differently for VM
+var vmMetricDepMap = map[string]map[string][]string{
+	"cpu": map[string][]string{
+		"usage":    []string{"cpu.usage.average"},
+		"usagemhz": []string{"cpu.usagemhz.average"},
+		"ready":    []string{"cpu.ready.summation"},
+		"costop":   []string{"cpu.costop.summation"},
+		"wait":     []string{"cpu.wait.summation"},
+		"swapwait": []string{"cpu.swapwait.summation"},
+	},
+	"mem": map[string][]string{
+		"active":      []string{"mem.active.average"},
+		"consumed":    []string{"mem.consumed.average"},
+		"balloon":     []string{"mem.vmmemctl.average"},
+		"swapped":     []string{"mem.swapped.average"},
+		"compressed":  []string{"mem.compressed.average"},
+		"granted":     []string{"mem.granted.average"},
+		"overhead":    []string{"mem.overhead.average"},
+		"swapinRate":  []string{"mem.swapinRate.average"},
+		"swapoutRate": []string{"mem.swapoutRate.average"},
+	},
+	"net": map[string][]string{
+		"kbrateTx":    []string{"net.bytesTx.average"},
+		"kbrateRx":    []string{"net.bytesRx.average"},
+		"packetsTx":   []string{"net.packetsTx.summation"},
+		"packetsRx":   []string{"net.packetsRx.summation"},
+		"droppedTx":   []string{"net.droppedTx.summation"},
+		"droppedRx":   []string{"net.droppedRx.summation"},
+		"broadcastTx": []string{"net.broadcastTx.summation"},
+		"broadcastRx": []string{"net.broadcastRx.summation"},
+		"multicastTx": []string{"net.multicastTx.summation"},
+		"multicastRx": []string{"net.multicastRx.summation"},
+		"usage":       []string{"net.usage.average"},
+	},
+	"power": map[string][]string{
+		"power":  []string{"power.power.average"},
+		"energy": []string{"power.energy.summation"},
+	},
 	"virtualDisk": map[string][]string{
 		"readIops":        []string{"virtualDisk.numberReadAveraged.average"},
 		"writeIops":       []string{"virtualDisk.numberWriteAveraged.average"},
@@ -99,14 +248,57 @@ var metricDepMap = map[string]map[string][]string{
 	},
 }
 
+// Datastore metric dependency map
+// Datastores have no metric groups, so Snap metrics are mapped directly to vSphere perf counters
+// Metrics calculated from datastore summary (capacity, free, provisioned) have no dependencies
+// Datastore IO counters are collected on hosts accessing the datastore, with datastore UUID as an instance
+var datastoreMetricDepMap = map[string][]string{
+	"readIops":        []string{"datastore.numberReadAveraged.average"},
+	"writeIops":       []string{"datastore.numberWriteAveraged.average"},
+	"readThroughput":  []string{"datastore.read.average"},
+	"writeThroughput": []string{"datastore.write.average"},
+	"readLatency":     []string{"datastore.totalReadLatency.average"},
+	"writeLatency":    []string{"datastore.totalWriteLatency.average"},
+}
+
+// Prefix of vSAN datastore UUID in datastore URL
+const vsanURLPrefix = "vsan:"
+
+// Datastore metrics averaged over hosts accessing datastore, values of other datastore counter metrics are summed up
+var datastoreAveragedMetrics = map[string]bool{
+	"readLatency":  true,
+	"writeLatency": true,
+}
+
+// Cluster metric dependency map
+// Cluster perf counters are available only for historical intervals
+// Metrics calculated from cluster summary (summary group) have no dependencies
+var clusterMetricDepMap = map[string]map[string][]string{
+	"clusterServices": map[string][]string{
+		"effectivecpu": []string{"clusterServices.effectivecpu.average"},
+		"effectivemem": []string{"clusterServices.effectivemem.average"},
+		"failover":     []string{"clusterServices.failover.latest"},
+	},
+	"cpu": map[string][]string{
+		"usage": []string{"cpu.usage.average"},
+	},
+	"mem": map[string][]string{
+		"usage": []string{"mem.usage.average"},
+	},
+}
+
 // New returns instance of VsphereCollector
 func New(isTest bool) *Collector {
 	collector := &Collector{}
 	collector.GovmomiResources = &govmomiClient{}
+	collector.eventCursors = map[eventCursor]eventCursorState{}
 	if isTest {
-		collector.GovmomiResources.api = &mockAPI{}
+		// All pooled sessions share one mock, so tests can set its flags before collection
+		mock := &mockAPI{}
+		collector.GovmomiResources.api = mock
+		collector.GovmomiResources.newAPI = func() API { return mock }
 	} else {
-		collector.GovmomiResources.api = &govmomiAPI{}
+		collector.GovmomiResources.newAPI = func() API { return &govmomiAPI{} }
 	}
 
 	return collector
@@ -125,11 +317,17 @@ func (c *Collector) preparePerfMetricID(ctx context.Context, fullCounterName str
 	return metric, nil
 }
 
-// updateQuerySpecMap updates query spec map with new PerfMetricIds (based on given metric name and entity reference)
-func (c *Collector) updateQuerySpecMap(ctx context.Context, querySpecs perfQuerySpecMap, interval int32, group string, metric string, entityName string, entityRef types.ManagedObjectReference) error {
+// updateQuerySpecMap updates query spec map with new PerfMetricIds (based on given counter names and entity reference)
+// Query specs are keyed by entity reference, as entities with the same name can exist in different clusters and datacenters
+func (c *Collector) updateQuerySpecMap(ctx context.Context, querySpecs perfQuerySpecMap, interval int32, counterFullNames []string, entityRef types.ManagedObjectReference) error {
+	// Metrics without counter dependencies (i.e. calculated from entity properties) do not need perf query
+	if len(counterFullNames) == 0 {
+		return nil
+	}
+
 	// Initialize query spec map entry if needed
-	if _, ok := querySpecs[entityName]; !ok {
-		querySpecs[entityName] = types.PerfQuerySpec{
+	if _, ok := querySpecs[entityRef.Value]; !ok {
+		querySpecs[entityRef.Value] = types.PerfQuerySpec{
 			Entity:     entityRef,
 			IntervalId: interval,
 			MaxSample:  1,
@@ -138,37 +336,35 @@ func (c *Collector) updateQuerySpecMap(ctx context.Context, querySpecs perfQuery
 		}
 	}
 
-	counterFullNames := metricDepMap[group][metric]
-	if counterFullNames != nil {
-		for _, ctr := range counterFullNames {
-			// Prepare types.PerfMetricId object based on counter name
-			ctrMetricID, err := c.preparePerfMetricID(ctx, ctr)
-			if err != nil {
-				return err
-			}
+	for _, ctr := range counterFullNames {
+		// Prepare types.PerfMetricId object based on counter name
+		ctrMetricID, err := c.preparePerfMetricID(ctx, ctr)
+		if err != nil {
+			return err
+		}
 
-			// Add prepared metric to query spec map (for selected entity), avoid duplicates
-			entitySpec := querySpecs[entityName]
-			duplicate := false
-			for _, mID := range entitySpec.MetricId {
-				if mID.CounterId == ctrMetricID.CounterId {
-					duplicate = true
-				}
-			}
-			if !duplicate {
-				entitySpec.MetricId = append(entitySpec.MetricId, *ctrMetricID)
-				querySpecs[entityName] = entitySpec
+		// Add prepared metric to query spec map (for selected entity), avoid duplicates
+		entitySpec := querySpecs[entityRef.Value]
+		duplicate := false
+		for _, mID := range entitySpec.MetricId {
+			if mID.CounterId == ctrMetricID.CounterId {
+				duplicate = true
 			}
 		}
+		if !duplicate {
+			entitySpec.MetricId = append(entitySpec.MetricId, *ctrMetricID)
+			querySpecs[entityRef.Value] = entitySpec
+		}
 	}
 
 	return nil
 }
 
-// buildQuerySpecsForMetrics builds slice of perf counter queries for all counters, hosts and virtual machines provided in metric namespaces
+// buildQuerySpecsForMetrics builds slice of perf counter queries for all counters, hosts, virtual machines and datastores provided in metric namespaces
 func (c *Collector) buildQuerySpecsForMetrics(ctx context.Context, mts []plugin.Metric) ([]types.PerfQuerySpec, error) {
 	hostQuerySpecs := make(perfQuerySpecMap)
 	vmQuerySpecs := make(perfQuerySpecMap)
+	clusterQuerySpecs := make(perfQuerySpecMap)
 	allQuerySpecs := []types.PerfQuerySpec{}
 
 	c.GovmomiResources.ClearCache()
@@ -184,7 +380,14 @@ func (c *Collector) buildQuerySpecsForMetrics(ctx context.Context, mts []plugin.
 			for _, host := range hosts {
 				isHost := m.Namespace[nsHostGroup].Value != "vm"
 				if isHost { // Retrieve vShpere HOST metrics
-					err := c.updateQuerySpecMap(ctx, hostQuerySpecs, defaultIntervalID, m.Namespace[nsHostGroup].Value, m.Namespace[nsHostMetric].Value, host.Name, host.Reference())
+					counters := hostMetricDepMap[m.Namespace[nsHostGroup].Value][m.Namespace[nsHostMetric].Value]
+					if m.Namespace[nsHostGroup].Value == counterNs {
+						counters, err = c.findRawCounterNames(ctx, m.Namespace, nsHostCounterGroup)
+						if err != nil {
+							return nil, err
+						}
+					}
+					err := c.updateQuerySpecMap(ctx, hostQuerySpecs, defaultIntervalID, counters, host.Reference())
 					if err != nil {
 						return nil, err
 					}
@@ -194,14 +397,60 @@ func (c *Collector) buildQuerySpecsForMetrics(ctx context.Context, mts []plugin.
 					if err != nil {
 						return nil, err
 					}
+					counters := vmMetricDepMap[m.Namespace[nsVMGroup].Value][m.Namespace[nsVMMetric].Value]
+					if m.Namespace[nsVMGroup].Value == counterNs {
+						counters, err = c.findRawCounterNames(ctx, m.Namespace, nsVMCounterGroup)
+						if err != nil {
+							return nil, err
+						}
+					}
 					for _, vm := range vms {
-						err := c.updateQuerySpecMap(ctx, vmQuerySpecs, defaultIntervalID, m.Namespace[nsVMGroup].Value, m.Namespace[nsVMMetric].Value, vm.Name, vm.Reference())
+						err := c.updateQuerySpecMap(ctx, vmQuerySpecs, defaultIntervalID, counters, vm.Reference())
 						if err != nil {
 							return nil, err
 						}
 					}
 				}
 			}
+		} else if m.Namespace[nsSource].Value == "datastore" {
+			// Retrieve datastores with name given in namespace entry
+			datastores, err := c.GovmomiResources.FindDatastores(ctx, m.Namespace[nsDatastore].Value)
+			if err != nil {
+				return nil, err
+			}
+
+			// Datastore IO counters are not collected for datastore entity, so they are queried on hosts accessing datastore
+			counters := datastoreMetricDepMap[m.Namespace[nsDatastoreMetric].Value]
+			if len(counters) == 0 {
+				continue
+			}
+			for _, ds := range datastores {
+				hosts, err := c.GovmomiResources.FindDatastoreHosts(ctx, ds)
+				if err != nil {
+					return nil, err
+				}
+				for _, host := range hosts {
+					err := c.updateQuerySpecMap(ctx, hostQuerySpecs, defaultIntervalID, counters, host.Reference())
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+		} else if m.Namespace[nsSource].Value == "cluster" {
+			// Retrieve clusters with name given in namespace entry
+			clusters, err := c.GovmomiResources.FindClusters(ctx, m.Namespace[nsCluster].Value)
+			if err != nil {
+				return nil, err
+			}
+
+			// Real-time statistics are not available for clusters, so historical interval is used
+			counters := clusterMetricDepMap[m.Namespace[nsClusterGroup].Value][m.Namespace[nsClusterMetric].Value]
+			for _, cluster := range clusters {
+				err := c.updateQuerySpecMap(ctx, clusterQuerySpecs, historicalIntervalID, counters, cluster.Reference())
+				if err != nil {
+					return nil, err
+				}
+			}
 		}
 	}
 
@@ -211,14 +460,46 @@ func (c *Collector) buildQuerySpecsForMetrics(ctx context.Context, mts []plugin.
 	for _, qs := range vmQuerySpecs {
 		allQuerySpecs = append(allQuerySpecs, qs)
 	}
-
-	if len(allQuerySpecs) == 0 {
-		return nil, fmt.Errorf("cannot build query spec based on provided namespaces")
+	for _, qs := range clusterQuerySpecs {
+		allQuerySpecs = append(allQuerySpecs, qs)
 	}
 
 	return allQuerySpecs, nil
 }
 
+// findRawCounterNames returns full names of all counters matching group, name and rollup type
+// given in raw counter namespace (starting at nsCounterGroup entry)
+func (c *Collector) findRawCounterNames(ctx context.Context, ns plugin.Namespace, nsCounterGroup int) ([]string, error) {
+	counters, err := c.GovmomiResources.FindCounters(ctx,
+		ns[nsCounterGroup].Value,
+		ns[nsCounterGroup+nsCounterName].Value,
+		ns[nsCounterGroup+nsCounterRollup].Value)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, ctr := range counters {
+		names = append(names, ctr.GroupInfo.GetElementDescription().Key+"."+ctr.NameInfo.GetElementDescription().Key+"."+fmt.Sprint(ctr.RollupType))
+	}
+	return names, nil
+}
+
+// rawCounterNs returns copy of raw counter namespace with group, name, rollup type and instance entries
+// (starting at nsCounterGroup entry) filled with values from given parsed response
+func (c *Collector) rawCounterNs(ns plugin.Namespace, nsCounterGroup int, v parsedQueryResponse) plugin.Namespace {
+	result := plugin.CopyNamespace(ns)
+
+	// Counter full name has group.name.rollup format
+	groupEnd := strings.Index(v.counterFullName, ".")
+	rollupStart := strings.LastIndex(v.counterFullName, ".")
+	result[nsCounterGroup].Value = v.counterFullName[:groupEnd]
+	result[nsCounterGroup+nsCounterName].Value = v.counterFullName[groupEnd+1 : rollupStart]
+	result[nsCounterGroup+nsCounterRollup].Value = v.counterFullName[rollupStart+1:]
+	result[nsCounterGroup+nsCounterInstance].Value = v.instance
+
+	return result
+}
+
 // instanceToNs converts instance name to namespace entry
 // As vSphere returns empty instance name for aggregated metrics, this function replaces it with predefined namespace entry
 func (c *Collector) instanceToNs(instance string) string {
@@ -285,6 +566,7 @@ func (c *Collector) buildParsedQueryResponses(ctx context.Context, entity types.
 		result = append(result, parsedQueryResponse{
 			hostName:        hostName,
 			vmName:          vmName,
+			entityRef:       entityRef,
 			counterFullName: counterGroup + "." + counterName,
 			instance:        c.instanceToNs(metric.Id.Instance),
 			data:            metricData,
@@ -308,17 +590,43 @@ func (c *Collector) parsePerfQueryResponse(ctx context.Context, response *types.
 	return results, nil
 }
 
+// datastoreInstances returns names of instances used by vSphere for given datastore in host datastore counters
+// Instance is datastore UUID, taken from the last element of datastore URL, which has the same format for VMFS and NFS datastores
+// (ds:///vmfs/volumes/<uuid>/, NFS datastores get UUID generated by ESXi). URL of vSAN datastore contains vsan: prefix
+// (ds:///vmfs/volumes/vsan:<uuid>/), so UUID is matched both with and without the prefix
+func datastoreInstances(ds mo.Datastore) []string {
+	if ds.Info == nil {
+		return nil
+	}
+	parts := strings.Split(strings.TrimSuffix(ds.Info.GetDatastoreInfo().Url, "/"), "/")
+	uuid := parts[len(parts)-1]
+	if uuid == "" {
+		return nil
+	}
+	if strings.HasPrefix(uuid, vsanURLPrefix) {
+		return []string{uuid, strings.TrimPrefix(uuid, vsanURLPrefix)}
+	}
+	return []string{uuid}
+}
+
+// summationToPercent converts counter value summed up during given interval (in milliseconds)
+// to the percentage of that interval
+func summationToPercent(value int64, intervalID int32) float64 {
+	return float64(value) / float64(intervalID*1000) * 100
+}
+
 // Filter parsed metrics based on given criteria
-func (c *Collector) filterQuery(parsedQuery []parsedQueryResponse, hostName string, vmName string, counterFullNames []string, instance string) []parsedQueryResponse {
+// Metrics are matched by entity reference, so entities with the same name in different clusters and datacenters are not mixed up
+func (c *Collector) filterQuery(parsedQuery []parsedQueryResponse, entityRef types.ManagedObjectReference, counterFullNames []string, instance string) []parsedQueryResponse {
 	result := []parsedQueryResponse{}
 	for _, q := range parsedQuery {
-		if (q.hostName == hostName || hostName == "*") &&
-			(q.vmName == vmName || vmName == "*") {
-			for _, counterFullName := range counterFullNames {
-				if q.counterFullName == counterFullName {
-					if q.instance == instance || instance == "*" {
-						result = append(result, q)
-					}
+		if q.entityRef != entityRef {
+			continue
+		}
+		for _, counterFullName := range counterFullNames {
+			if q.counterFullName == counterFullName {
+				if q.instance == instance || instance == "*" {
+					result = append(result, q)
 				}
 			}
 		}
@@ -332,13 +640,34 @@ func (c *Collector) CollectMetrics(mts []plugin.Metric) ([]plugin.Metric, error)
 		return nil, fmt.Errorf("No metrics specified")
 	}
 
-	metrics := []plugin.Metric{}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	ctx := context.Background()
 	if err := c.GovmomiResources.Init(ctx, mts[0].Config); err != nil {
 		return nil, fmt.Errorf("unable to initialize: %v", err)
 	}
 
+	metrics, err := c.collectMetrics(ctx, mts)
+	if err != nil && c.GovmomiResources.Recover(ctx, err) {
+		// Session was lost during collection (i.e. vCenter restart), retry once using new session
+		metrics, err = c.collectMetrics(ctx, mts)
+	}
+	return metrics, err
+}
+
+// collectMetrics collects requested metrics using already initialized session
+func (c *Collector) collectMetrics(ctx context.Context, mts []plugin.Metric) ([]plugin.Metric, error) {
+	metrics := []plugin.Metric{}
 	c.GovmomiResources.ClearCache()
+	c.GovmomiResources.SetOptionalProperties(c.optionalProperties(mts))
+
+	// Names of resource pools used to tag VM metrics, mapped by resource pool reference value
+	var poolNames map[string]string
+
+	// Events are read from the time previous collection of the same metrics ended, cursor is moved only if collection succeeds
+	cursor := c.eventCursorFor(mts)
+	var eventsUntil time.Time
 
 	// Build list of query specs to send in one packet
 	querySpecs, err := c.buildQuerySpecsForMetrics(ctx, mts)
@@ -346,16 +675,19 @@ func (c *Collector) CollectMetrics(mts []plugin.Metric) ([]plugin.Metric, error)
 		return nil, err
 	}
 
-	// Retrieve metric data
-	perfQuery, err := c.GovmomiResources.PerfQuery(ctx, querySpecs)
-	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve query perf response: %v", err)
-	}
+	// Retrieve metric data, skip perf query if requested metrics do not depend on any counter
+	results := []parsedQueryResponse{}
+	if len(querySpecs) > 0 {
+		perfQuery, err := c.GovmomiResources.PerfQuery(ctx, querySpecs)
+		if err != nil {
+			return nil, wrapError("unable to retrieve query perf response", err)
+		}
 
-	// Parse retrieved metric data (retrieve host name, vm name and instance id for each counter)
-	results, err := c.parsePerfQueryResponse(ctx, perfQuery)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse query perf response: %v", err)
+		// Parse retrieved metric data (retrieve host name, vm name and instance id for each counter)
+		results, err = c.parsePerfQueryResponse(ctx, perfQuery)
+		if err != nil {
+			return nil, wrapError("unable to parse query perf response", err)
+		}
 	}
 
 	// Convert retrieved metrics to snap namespaces
@@ -366,16 +698,81 @@ func (c *Collector) CollectMetrics(mts []plugin.Metric) ([]plugin.Metric, error)
 			if err != nil {
 				return nil, err
 			}
+			hostMetricsStart := len(metrics)
 			for _, host := range hosts {
 				isHost := m.Namespace[nsHostGroup].Value != "vm"
-				if isHost {
+				if isHost && m.Namespace[nsHostGroup].Value == counterNs {
+					// Return raw perf counter host metrics
+					counters, err := c.findRawCounterNames(ctx, m.Namespace, nsHostCounterGroup)
+					if err != nil {
+						return nil, err
+					}
+					hostValues := c.filterQuery(results, host.Reference(), counters, m.Namespace[nsHostCounterGroup+nsCounterInstance].Value)
+					for _, v := range hostValues {
+						metric := plugin.Metric{
+							Namespace: c.rawCounterNs(m.Namespace, nsHostCounterGroup, v),
+							Data:      v.data,
+						}
+						metric.Namespace[nsHost].Value = v.hostName
+
+						metrics = append(metrics, metric)
+					}
+				} else if isHost {
 					hostGroup := m.Namespace[nsHostGroup].Value
 					hostInstance := m.Namespace[nsHostInstance].Value
 					hostMetric := m.Namespace[nsHostMetric].Value
 
 					// Filter all counter values for host and instance given in namespace (both can be *)
 					// Counter names for selected namespace are retrieved from metric dependency map
-					hostValues := c.filterQuery(results, host.Name, "", metricDepMap[hostGroup][hostMetric], hostInstance)
+					hostValues := c.filterQuery(results, host.Reference(), hostMetricDepMap[hostGroup][hostMetric], hostInstance)
+
+					// Return host hardware health sensor metrics, sensor name is used as an instance
+					if hostGroup == "sensor" {
+						for _, sensor := range hostSensors(host) {
+							if sensor.name != hostInstance && hostInstance != "*" {
+								continue
+							}
+							metric := plugin.Metric{
+								Namespace: plugin.CopyNamespace(m.Namespace),
+								Tags:      map[string]string{tagSensorType: sensor.sensorType},
+							}
+							metric.Namespace[nsHost].Value = host.Name
+							metric.Namespace[nsHostInstance].Value = sensor.name
+
+							switch hostMetric {
+							case "reading":
+								if sensor.reading == nil {
+									continue
+								}
+								metric.Data = *sensor.reading
+								metric.Tags[tagUnit] = sensor.unit
+							case "healthState":
+								metric.Data = healthStateCodes[sensor.healthState]
+							default:
+								continue
+							}
+
+							metrics = append(metrics, metric)
+						}
+						continue
+					}
+
+					// Return host metrics calculated from host properties
+					if propertyFunc, ok := hostPropertyMetrics[hostGroup][hostMetric]; ok {
+						data := propertyFunc(host)
+						if data == nil {
+							continue
+						}
+						metric := plugin.Metric{
+							Namespace: plugin.CopyNamespace(m.Namespace),
+							Data:      data,
+						}
+						metric.Namespace[nsHost].Value = host.Name
+						metric.Namespace[nsHostInstance].Value = aggregatedNs
+
+						metrics = append(metrics, metric)
+						continue
+					}
 
 					// Return host-level and multiple counter dependency metrics
 					metric := plugin.Metric{
@@ -428,112 +825,1234 @@ func (c *Collector) CollectMetrics(mts []plugin.Metric) ([]plugin.Metric, error)
 					vmInstance := m.Namespace[nsVMInstance].Value
 					vmMetric := m.Namespace[nsVMMetric].Value
 
-					vmValues := c.filterQuery(results, host.Name, vmName, metricDepMap[vmGroup][vmMetric], vmInstance)
+					vms, err := c.GovmomiResources.FindVMs(ctx, host, vmName)
+					if err != nil {
+						return nil, err
+					}
+					for _, vm := range vms {
+						vmValues := c.filterQuery(results, vm.Reference(), vmMetricDepMap[vmGroup][vmMetric], vmInstance)
+
+						// Return raw perf counter VM metrics
+						if vmGroup == counterNs {
+							counters, err := c.findRawCounterNames(ctx, m.Namespace, nsVMCounterGroup)
+							if err != nil {
+								return nil, err
+							}
+							vmValues = c.filterQuery(results, vm.Reference(), counters, m.Namespace[nsVMCounterGroup+nsCounterInstance].Value)
+							for _, v := range vmValues {
+								metric := plugin.Metric{
+									Namespace: c.rawCounterNs(m.Namespace, nsVMCounterGroup, v),
+									Data:      v.data,
+								}
+								metric.Namespace[nsHost].Value = v.hostName
+								metric.Namespace[nsVM].Value = v.vmName
+
+								metrics = append(metrics, metric)
+							}
+							continue
+						}
 
-					for _, v := range vmValues {
-						metric := plugin.Metric{
-							Namespace: plugin.CopyNamespace(m.Namespace),
-							Data:      v.data,
+						// Tag VM metrics with name of resource pool VM belongs to
+						tags := map[string]string{}
+						if vm.ResourcePool != nil {
+							if poolNames == nil {
+								// Resource pools are retrieved once per collection, VM metrics are not tagged if retrieval fails
+								names, err := c.GovmomiResources.FindResourcePoolNames(ctx)
+								if err != nil {
+									names = map[string]string{}
+								}
+								poolNames = names
+							}
+							if poolName, ok := poolNames[vm.ResourcePool.Value]; ok {
+								tags[tagResourcePool] = poolName
+							}
 						}
-						metric.Namespace[nsHost].Value = v.hostName
-						metric.Namespace[nsVM].Value = v.vmName
-						metric.Namespace[nsVMInstance].Value = v.instance
 
-						metrics = append(metrics, metric)
+						// Return VM metrics calculated from VM properties
+						if propertyFunc, ok := vmPropertyMetrics[vmGroup][vmMetric]; ok {
+							data := propertyFunc(vm)
+							if data == nil {
+								continue
+							}
+							metric := plugin.Metric{
+								Namespace: plugin.CopyNamespace(m.Namespace),
+								Data:      data,
+								Tags:      tags,
+							}
+							metric.Namespace[nsHost].Value = host.Name
+							metric.Namespace[nsVM].Value = vm.Name
+							metric.Namespace[nsVMInstance].Value = aggregatedNs
+
+							metrics = append(metrics, metric)
+							continue
+						}
+
+						// Return per-instance VM metrics calculated from VM properties
+						if propertyFunc, ok := vmInstancePropertyMetrics[vmGroup][vmMetric]; ok {
+							values := propertyFunc(vm)
+							instances := []string{}
+							for instance := range values {
+								if instance == vmInstance || vmInstance == "*" {
+									instances = append(instances, instance)
+								}
+							}
+							sort.Strings(instances)
+
+							for _, instance := range instances {
+								metric := plugin.Metric{
+									Namespace: plugin.CopyNamespace(m.Namespace),
+									Data:      values[instance],
+									Tags:      tags,
+								}
+								metric.Namespace[nsHost].Value = host.Name
+								metric.Namespace[nsVM].Value = vm.Name
+								metric.Namespace[nsVMInstance].Value = instance
+
+								metrics = append(metrics, metric)
+							}
+							continue
+						}
+
+						for _, v := range vmValues {
+							metric := plugin.Metric{
+								Namespace: plugin.CopyNamespace(m.Namespace),
+								Data:      v.data,
+								Tags:      tags,
+							}
+							metric.Namespace[nsHost].Value = v.hostName
+							metric.Namespace[nsVM].Value = v.vmName
+							metric.Namespace[nsVMInstance].Value = v.instance
+
+							// VM derived metrics
+							switch vmGroup + "." + vmMetric {
+
+							// CPU metrics
+							case "cpu.usage":
+								metric.Data = float64(v.data) / 100
+							case "cpu.ready", "cpu.costop":
+								// Aggregated value is a sum for all vCPUs, so it has to be normalized
+								numCPU := int32(1)
+								if v.instance == aggregatedNs && vm.Summary.Config.NumCpu > 0 {
+									numCPU = vm.Summary.Config.NumCpu
+								}
+								metric.Data = summationToPercent(v.data, defaultIntervalID) / float64(numCPU)
+
+							// Memory metrics
+							case "mem.active", "mem.consumed", "mem.balloon", "mem.swapped", "mem.compressed", "mem.granted", "mem.overhead":
+								metric.Data = v.data / unitKilobyte
+							case "mem.swapinRate", "mem.swapoutRate":
+								metric.Data = float64(v.data) / unitKilobyte
+							}
+
+							metrics = append(metrics, metric)
+						}
 					}
 				}
 			}
-		}
-	}
 
-	return metrics, nil
-}
+			// Tag host and VM metrics with names of cluster and datacenter host belongs to
+			if err := c.tagHostMetrics(ctx, metrics[hostMetricsStart:], hosts); err != nil {
+				return nil, err
+			}
+		} else if m.Namespace[nsSource].Value == "datastore" {
+			datastores, err := c.GovmomiResources.FindDatastores(ctx, m.Namespace[nsDatastore].Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, ds := range datastores {
+				dsInstance := m.Namespace[nsDatastoreInstance].Value
+				dsMetric := m.Namespace[nsDatastoreMetric].Value
+
+				// Tag datastore metrics with name of datacenter datastore belongs to
+				// Datastore can be shared between clusters, so it is not tagged with cluster name
+				tags, err := c.entityTags(ctx, ds.Reference(), nil)
+				if err != nil {
+					return nil, err
+				}
 
-func (c *Collector) createDsNs(metric string) plugin.Namespace {
-	return plugin.NewNamespace(vendor, class, name, "datastore").
-		AddDynamicElement("datastore_name", "Name of dataqstore").
-		AddDynamicElement("instance", "Metric instance ID").
-		AddStaticElement(metric)
-}
+				// Return metrics calculated from datastore summary
+				metric := plugin.Metric{
+					Namespace: plugin.CopyNamespace(m.Namespace),
+					Tags:      tags,
+				}
+				metric.Namespace[nsDatastore].Value = ds.Name
+				metric.Namespace[nsDatastoreInstance].Value = aggregatedNs
+
+				switch dsMetric {
+				case "capacity":
+					metric.Data = ds.Summary.Capacity / unitMegabyte
+					metrics = append(metrics, metric)
+					continue
+				case "free":
+					metric.Data = ds.Summary.FreeSpace / unitMegabyte
+					metrics = append(metrics, metric)
+					continue
+				case "provisioned":
+					metric.Data = (ds.Summary.Capacity - ds.Summary.FreeSpace + ds.Summary.Uncommitted) / unitMegabyte
+					metrics = append(metrics, metric)
+					continue
+				}
 
-func (c *Collector) createHostNs(group string, metric string) plugin.Namespace {
-	return plugin.NewNamespace(vendor, class, name, "host").
-		AddDynamicElement("hostname", "Name of host, it can be IP address").
-		AddStaticElement(group).
-		AddDynamicElement("instance", "Metric instance ID").
-		AddStaticElement(metric)
-}
+				// Return counter-level datastore metrics, aggregated from values reported by all hosts accessing datastore
+				if dsInstance != aggregatedNs && dsInstance != "*" {
+					continue
+				}
+				hosts, err := c.GovmomiResources.FindDatastoreHosts(ctx, ds)
+				if err != nil {
+					return nil, err
+				}
+				dsValues := []parsedQueryResponse{}
+				for _, host := range hosts {
+					for _, instance := range datastoreInstances(ds) {
+						dsValues = append(dsValues, c.filterQuery(results, host.Reference(), datastoreMetricDepMap[dsMetric], instance)...)
+					}
+				}
+				if len(dsValues) == 0 {
+					continue
+				}
 
-func (c *Collector) createVMNs(group string, metric string) plugin.Namespace {
-	return plugin.NewNamespace(vendor, class, name, "host").
-		AddDynamicElement("hostname", "Name of host, it can be IP address").
-		AddStaticElement("vm").
-		AddDynamicElement("vmname", "Name of virtual machine").
-		AddStaticElement(group).
-		AddDynamicElement("instance", "Metric instance ID").
-		AddStaticElement(metric)
-}
+				sum := int64(0)
+				for _, v := range dsValues {
+					sum += v.data
+				}
+				metric.Data = sum
+				if datastoreAveragedMetrics[dsMetric] {
+					metric.Data = sum / int64(len(dsValues))
+				}
+				metrics = append(metrics, metric)
+			}
+		} else if m.Namespace[nsSource].Value == "cluster" {
+			clusters, err := c.GovmomiResources.FindClusters(ctx, m.Namespace[nsCluster].Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, cluster := range clusters {
+				clusterGroup := m.Namespace[nsClusterGroup].Value
+				clusterInstance := m.Namespace[nsClusterInstance].Value
+				clusterMetric := m.Namespace[nsClusterMetric].Value
+
+				tags, err := c.entityTags(ctx, cluster.Reference(), &cluster)
+				if err != nil {
+					return nil, err
+				}
 
-// GetMetricTypes returns available metrics
-func (c *Collector) GetMetricTypes(cfg plugin.Config) ([]plugin.Metric, error) {
-	metrics := []plugin.Metric{}
+				// Return metrics calculated from cluster summary
+				if clusterGroup == "summary" {
+					if cluster.Summary == nil {
+						continue
+					}
+					summary := cluster.Summary.GetComputeResourceSummary()
 
-	// HOST - MEMORY
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("mem", "usage"),
-		Description: "Memory usage in megabytes",
-		Unit:        "megabyte"})
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("mem", "free"),
-		Description: "Free memory in megabytes",
-		Unit:        "megabyte"})
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("mem", "available"),
-		Description: "Available memory in megabytes",
-		Unit:        "megabyte"})
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("mem", "swapUsage"),
-		Description: "Sum of memory swapped of all powered on VMs and vSphere services on the host.",
-		Unit:        "megabyte"})
+					metric := plugin.Metric{
+						Namespace: plugin.CopyNamespace(m.Namespace),
+						Tags:      tags,
+					}
+					metric.Namespace[nsCluster].Value = cluster.Name
+					metric.Namespace[nsClusterInstance].Value = aggregatedNs
+
+					switch clusterMetric {
+					case "numHosts":
+						metric.Data = summary.NumHosts
+					case "numEffectiveHosts":
+						metric.Data = summary.NumEffectiveHosts
+					case "totalCpu":
+						metric.Data = summary.TotalCpu
+					case "totalMemory":
+						metric.Data = summary.TotalMemory / unitMegabyte
+					default:
+						continue
+					}
+					metrics = append(metrics, metric)
+					continue
+				}
 
-	// HOST - CPU
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("cpu", "idle"),
-		Description: "Total & per-core time CPU spent in an idle as a percentage during the last 20s",
-		Unit:        "percent"})
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("cpu", "wait"),
-		Description: "Total time CPU spent in a wait state as a percentage during the last 20s",
-		Unit:        "percent"})
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("cpu", "load"),
-		Description: "CPU load average over last 1 minute",
-		Unit:        "number"})
+				// Return counter-level cluster metrics
+				clusterValues := c.filterQuery(results, cluster.Reference(), clusterMetricDepMap[clusterGroup][clusterMetric], clusterInstance)
+				for _, v := range clusterValues {
+					metric := plugin.Metric{
+						Namespace: plugin.CopyNamespace(m.Namespace),
+						Data:      v.data,
+						Tags:      tags,
+					}
+					metric.Namespace[nsCluster].Value = cluster.Name
+					metric.Namespace[nsClusterInstance].Value = v.instance
 
-	// HOST - NET
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("net", "kbrateTx"),
-		Description: "Average amount of data transmitted per second during the last 20s",
-		Unit:        "kiloBytesPerSecond"})
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("net", "kbrateRx"),
-		Description: "Average amount of data received per second during the last 20s",
-		Unit:        "kiloBytesPerSecond"})
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("net", "packetsTx"),
-		Description: "Number of packets transmitted during the last 20s",
-		Unit:        "number"})
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createHostNs("net", "packetsRx"),
-		Description: "Number of packets received during the last 20s",
-		Unit:        "number"})
+					// Cluster derived metrics
+					switch clusterGroup + "." + clusterMetric {
+					case "cpu.usage", "mem.usage":
+						metric.Data = float64(v.data) / 100
+					}
 
-	// VM - VIRTUALDISK
-	metrics = append(metrics, plugin.Metric{
-		Namespace:   c.createVMNs("virtualDisk", "readIops"),
-		Description: "Read I/O operations per second",
-		Unit:        "number"})
-	metrics = append(metrics, plugin.Metric{
+					metrics = append(metrics, metric)
+				}
+			}
+		} else if m.Namespace[nsSource].Value == "resourcePool" {
+			pools, err := c.GovmomiResources.FindResourcePools(ctx, m.Namespace[nsResourcePool].Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, pool := range pools {
+				if pool.Summary == nil {
+					continue
+				}
+				summary := pool.Summary.GetResourcePoolSummary()
+
+				// Tag resource pool metrics with names of cluster and datacenter resource pool belongs to
+				// Resource pools of standalone hosts are tagged with datacenter name only
+				var cluster *mo.ClusterComputeResource
+				if pool.Owner.Type == "ClusterComputeResource" {
+					cluster, err = c.GovmomiResources.FindClusterByRef(ctx, pool.Owner)
+					if err != nil {
+						return nil, err
+					}
+				}
+				tags, err := c.entityTags(ctx, pool.Owner, cluster)
+				if err != nil {
+					return nil, err
+				}
+
+				// VMs of resource pool are listed with VM name as metric instance
+				if m.Namespace[nsResourcePoolGroup].Value == "vm" {
+					vms, err := c.GovmomiResources.FindResourcePoolVMs(ctx, pool, m.Namespace[nsResourcePoolInstance].Value)
+					if err != nil {
+						return nil, err
+					}
+					for _, vm := range vms {
+						metric := plugin.Metric{
+							Namespace: plugin.CopyNamespace(m.Namespace),
+							Tags:      tags,
+						}
+						metric.Namespace[nsResourcePool].Value = pool.Name
+						metric.Namespace[nsResourcePoolInstance].Value = vm.Name
+
+						switch m.Namespace[nsResourcePoolMetric].Value {
+						case "poweredOn":
+							metric.Data = 0
+							if vm.Summary.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn {
+								metric.Data = 1
+							}
+						default:
+							continue
+						}
+
+						metrics = append(metrics, metric)
+					}
+					continue
+				}
+
+				metric := plugin.Metric{
+					Namespace: plugin.CopyNamespace(m.Namespace),
+					Tags:      tags,
+				}
+				metric.Namespace[nsResourcePool].Value = pool.Name
+				metric.Namespace[nsResourcePoolInstance].Value = aggregatedNs
+
+				// Resource pool metrics are calculated from resource pool summary
+				switch m.Namespace[nsResourcePoolGroup].Value + "." + m.Namespace[nsResourcePoolMetric].Value {
+				case "cpu.usage":
+					if summary.QuickStats == nil {
+						continue
+					}
+					metric.Data = summary.QuickStats.OverallCpuUsage
+				case "cpu.reservation":
+					metric.Data = summary.Config.CpuAllocation.GetResourceAllocationInfo().Reservation
+				case "cpu.limit":
+					metric.Data = summary.Config.CpuAllocation.GetResourceAllocationInfo().Limit
+				case "cpu.shares":
+					shares := summary.Config.CpuAllocation.GetResourceAllocationInfo().Shares
+					if shares == nil {
+						continue
+					}
+					metric.Data = shares.Shares
+				case "mem.usage":
+					if summary.QuickStats == nil {
+						continue
+					}
+					metric.Data = summary.QuickStats.GuestMemoryUsage
+				case "mem.consumed":
+					if summary.QuickStats == nil {
+						continue
+					}
+					metric.Data = summary.QuickStats.HostMemoryUsage
+				case "mem.overhead":
+					if summary.QuickStats == nil {
+						continue
+					}
+					metric.Data = summary.QuickStats.OverheadMemory
+				case "mem.reservation":
+					metric.Data = summary.Config.MemoryAllocation.GetResourceAllocationInfo().Reservation
+				case "mem.limit":
+					metric.Data = summary.Config.MemoryAllocation.GetResourceAllocationInfo().Limit
+				case "mem.shares":
+					shares := summary.Config.MemoryAllocation.GetResourceAllocationInfo().Shares
+					if shares == nil {
+						continue
+					}
+					metric.Data = shares.Shares
+				case "summary.numVMs":
+					metric.Data = len(pool.Vm)
+				default:
+					continue
+				}
+
+				metrics = append(metrics, metric)
+			}
+		} else if m.Namespace[nsSource].Value == "alarm" {
+			alarms, err := c.GovmomiResources.FindTriggeredAlarms(ctx, m.Namespace[nsAlarmEntityType].Value, m.Namespace[nsAlarmEntity].Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range alarms {
+				if a.state.Alarm.Value != m.Namespace[nsAlarm].Value && m.Namespace[nsAlarm].Value != "*" {
+					continue
+				}
+
+				metric := plugin.Metric{
+					Namespace: plugin.CopyNamespace(m.Namespace),
+					Tags:      map[string]string{tagAlarmName: a.alarm.Info.Name},
+				}
+				metric.Tags[tagDatacenterName] = a.datacenterName
+				metric.Namespace[nsAlarmEntityType].Value = a.entityType
+				metric.Namespace[nsAlarmEntity].Value = a.entityName
+				metric.Namespace[nsAlarm].Value = a.state.Alarm.Value
+
+				// Alarm metrics are calculated from triggered alarm state
+				switch m.Namespace[nsAlarmMetric].Value {
+				case "severity":
+					metric.Data = overallStatusCodes[string(a.state.OverallStatus)]
+				case "acknowledged":
+					metric.Data = 0
+					if a.state.Acknowledged != nil && *a.state.Acknowledged {
+						metric.Data = 1
+					}
+				case "triggeredTime":
+					metric.Data = a.state.Time.Unix()
+				default:
+					continue
+				}
+
+				metrics = append(metrics, metric)
+			}
+		} else if m.Namespace[nsSource].Value == "event" {
+			eventType := m.Namespace[nsEventType].Value
+			events, until, err := c.GovmomiResources.FindEvents(ctx, c.eventCursors[cursor].until, eventType)
+			if err != nil {
+				return nil, err
+			}
+			eventsUntil = until
+
+			switch m.Namespace[nsEventMetric].Value {
+			case "count":
+				// Count events per type, explicitly requested event type is reported even if no events occurred
+				counts := map[string]int{}
+				if eventType != "*" {
+					counts[eventType] = 0
+				}
+				for _, e := range events {
+					counts[eventTypeName(e)]++
+				}
+				eventTypes := []string{}
+				for t := range counts {
+					eventTypes = append(eventTypes, t)
+				}
+				sort.Strings(eventTypes)
+
+				for _, t := range eventTypes {
+					metric := plugin.Metric{
+						Namespace: plugin.CopyNamespace(m.Namespace),
+						Data:      counts[t],
+					}
+					metric.Namespace[nsEventType].Value = t
+					metric.Namespace[nsEventInstance].Value = aggregatedNs
+
+					metrics = append(metrics, metric)
+				}
+			case "created":
+				// Return each event as separate metric, tagged with event details
+				for _, e := range events {
+					ev := e.GetEvent()
+					key := fmt.Sprint(ev.Key)
+					if key != m.Namespace[nsEventInstance].Value && m.Namespace[nsEventInstance].Value != "*" {
+						continue
+					}
+
+					metric := plugin.Metric{
+						Namespace: plugin.CopyNamespace(m.Namespace),
+						Data:      ev.CreatedTime.Unix(),
+						Tags: map[string]string{
+							tagMessage:  ev.FullFormattedMessage,
+							tagUserName: ev.UserName,
+						},
+					}
+					if ev.Host != nil {
+						metric.Tags[tagHostName] = ev.Host.Name
+					}
+					if ev.Vm != nil {
+						metric.Tags[tagVMName] = ev.Vm.Name
+					}
+					if ev.Datacenter != nil {
+						metric.Tags[tagDatacenterName] = ev.Datacenter.Name
+					}
+					metric.Namespace[nsEventType].Value = eventTypeName(e)
+					metric.Namespace[nsEventInstance].Value = key
+
+					metrics = append(metrics, metric)
+				}
+			}
+		}
+	}
+
+	if !eventsUntil.IsZero() {
+		c.eventCursors[cursor] = eventCursorState{until: eventsUntil, lastUsed: time.Now()}
+	}
+	c.evictEventCursors(time.Now())
+
+	return metrics, nil
+}
+
+// optionalProperties returns optional host and VM properties needed to calculate given metrics, mapped by entity type
+func (c *Collector) optionalProperties(mts []plugin.Metric) map[string][]string {
+	needed := map[string]map[string]bool{}
+	add := func(entityType string, groupProperties map[string][]string, group string) {
+		for g, groupProps := range groupProperties {
+			if g != group && group != "*" {
+				continue
+			}
+			if needed[entityType] == nil {
+				needed[entityType] = map[string]bool{}
+			}
+			for _, prop := range groupProps {
+				needed[entityType][prop] = true
+			}
+		}
+	}
+	for _, m := range mts {
+		if m.Namespace[nsSource].Value != "host" {
+			continue
+		}
+		if m.Namespace[nsHostGroup].Value == "vm" {
+			add("vm", vmGroupProperties, m.Namespace[nsVMGroup].Value)
+			continue
+		}
+		add("host", hostGroupProperties, m.Namespace[nsHostGroup].Value)
+	}
+
+	props := map[string][]string{}
+	for entityType, set := range needed {
+		for prop := range set {
+			props[entityType] = append(props[entityType], prop)
+		}
+		sort.Strings(props[entityType])
+	}
+	return props
+}
+
+// evictEventCursors removes cursors of tasks which have not collected events for longer than idle timeout,
+// next collection of such task starts reading events from current time
+func (c *Collector) evictEventCursors(now time.Time) {
+	for cursor, state := range c.eventCursors {
+		if now.Sub(state.lastUsed) > eventCursorIdleTimeout {
+			delete(c.eventCursors, cursor)
+		}
+	}
+}
+
+// eventCursorFor returns event cursor for given metrics, built from current connection and requested event metrics
+func (c *Collector) eventCursorFor(mts []plugin.Metric) eventCursor {
+	namespaces := []string{}
+	for _, m := range mts {
+		if m.Namespace[nsSource].Value == "event" {
+			namespaces = append(namespaces, strings.Join(m.Namespace.Strings(), "/"))
+		}
+	}
+	sort.Strings(namespaces)
+	key := c.GovmomiResources.key
+	return eventCursor{
+		url:                key.url,
+		datacenterName:     key.datacenterName,
+		clusterName:        key.clusterName,
+		standaloneHostName: key.standaloneHostName,
+		metrics:            strings.Join(namespaces, ","),
+	}
+}
+
+// tagHostMetrics tags given host and VM metrics with names of cluster (for clustered hosts) and datacenter their host belongs to
+func (c *Collector) tagHostMetrics(ctx context.Context, metrics []plugin.Metric, hosts []mo.HostSystem) error {
+	hostTags := map[string]map[string]string{}
+	for _, host := range hosts {
+		cluster, err := c.GovmomiResources.FindClusterByHost(ctx, host)
+		if err != nil {
+			return err
+		}
+		hostTags[host.Name], err = c.entityTags(ctx, host.Reference(), cluster)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range metrics {
+		for k, v := range hostTags[metrics[i].Namespace[nsHost].Value] {
+			if metrics[i].Tags == nil {
+				metrics[i].Tags = map[string]string{}
+			}
+			metrics[i].Tags[k] = v
+		}
+	}
+	return nil
+}
+
+// entityTags returns tags with name of datacenter entity with given reference belongs to,
+// and name of cluster if cluster is given
+func (c *Collector) entityTags(ctx context.Context, ref types.ManagedObjectReference, cluster *mo.ClusterComputeResource) (map[string]string, error) {
+	tags := map[string]string{}
+	if ref.Value == "" {
+		return tags, nil
+	}
+	dcName, err := c.GovmomiResources.FindDatacenterName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	tags[tagDatacenterName] = dcName
+	if cluster != nil {
+		tags[tagClusterName] = cluster.Name
+	}
+	return tags, nil
+}
+
+func (c *Collector) createDsNs(metric string) plugin.Namespace {
+	return plugin.NewNamespace(vendor, class, name, "datastore").
+		AddDynamicElement("datastore_name", "Name of datastore").
+		AddDynamicElement("instance", "Metric instance ID").
+		AddStaticElement(metric)
+}
+
+func (c *Collector) createClusterNs(group string, metric string) plugin.Namespace {
+	return plugin.NewNamespace(vendor, class, name, "cluster").
+		AddDynamicElement("cluster_name", "Name of cluster").
+		AddStaticElement(group).
+		AddDynamicElement("instance", "Metric instance ID").
+		AddStaticElement(metric)
+}
+
+func (c *Collector) createResourcePoolNs(group string, metric string) plugin.Namespace {
+	return plugin.NewNamespace(vendor, class, name, "resourcePool").
+		AddDynamicElement("resource_pool_name", "Name of resource pool").
+		AddStaticElement(group).
+		AddDynamicElement("instance", "Metric instance ID").
+		AddStaticElement(metric)
+}
+
+func (c *Collector) createAlarmNs(metric string) plugin.Namespace {
+	return plugin.NewNamespace(vendor, class, name, "alarm").
+		AddDynamicElement("entity_type", "Type of entity alarm is triggered on - cluster, host, vm or datastore").
+		AddDynamicElement("entity_name", "Name of entity alarm is triggered on").
+		AddDynamicElement("alarm_id", "ID of alarm").
+		AddStaticElement(metric)
+}
+
+func (c *Collector) createEventNs(metric string) plugin.Namespace {
+	return plugin.NewNamespace(vendor, class, name, "event").
+		AddDynamicElement("event_type", "Type of vCenter event, i.e. VmPoweredOnEvent").
+		AddDynamicElement("instance", "Event key, or aggr for metrics aggregated per event type").
+		AddStaticElement(metric)
+}
+
+func (c *Collector) createHostNs(group string, metric string) plugin.Namespace {
+	return plugin.NewNamespace(vendor, class, name, "host").
+		AddDynamicElement("hostname", "Name of host, it can be IP address").
+		AddStaticElement(group).
+		AddDynamicElement("instance", "Metric instance ID").
+		AddStaticElement(metric)
+}
+
+func (c *Collector) createHostCounterNs() plugin.Namespace {
+	return plugin.NewNamespace(vendor, class, name, "host").
+		AddDynamicElement("hostname", "Name of host, it can be IP address").
+		AddStaticElement(counterNs).
+		AddDynamicElement("group", "vSphere perf counter group").
+		AddDynamicElement("counter", "vSphere perf counter name").
+		AddDynamicElement("rollup", "vSphere perf counter rollup type").
+		AddDynamicElement("instance", "Metric instance ID")
+}
+
+func (c *Collector) createVMCounterNs() plugin.Namespace {
+	return plugin.NewNamespace(vendor, class, name, "host").
+		AddDynamicElement("hostname", "Name of host, it can be IP address").
+		AddStaticElement("vm").
+		AddDynamicElement("vmname", "Name of virtual machine").
+		AddStaticElement(counterNs).
+		AddDynamicElement("group", "vSphere perf counter group").
+		AddDynamicElement("counter", "vSphere perf counter name").
+		AddDynamicElement("rollup", "vSphere perf counter rollup type").
+		AddDynamicElement("instance", "Metric instance ID")
+}
+
+func (c *Collector) createVMNs(group string, metric string) plugin.Namespace {
+	return plugin.NewNamespace(vendor, class, name, "host").
+		AddDynamicElement("hostname", "Name of host, it can be IP address").
+		AddStaticElement("vm").
+		AddDynamicElement("vmname", "Name of virtual machine").
+		AddStaticElement(group).
+		AddDynamicElement("instance", "Metric instance ID").
+		AddStaticElement(metric)
+}
+
+// GetMetricTypes returns available metrics
+// If vCenter configuration is provided, metric catalog is built based on perf counters available on vCenter,
+// otherwise static metric catalog is returned
+func (c *Collector) GetMetricTypes(cfg plugin.Config) ([]plugin.Metric, error) {
+	metrics := c.staticMetricTypes()
+
+	if url, err := cfg.GetString("url"); err != nil || url == "" {
+		return metrics, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// vCenter may be unavailable or return incomplete data when plugin is loaded, in which case static catalog
+	// is returned instead of failing plugin load, and the error is kept for inspection
+	c.catalogErr = nil
+	ctx := context.Background()
+	if err := c.GovmomiResources.Init(ctx, cfg); err != nil {
+		c.catalogErr = wrapError("unable to initialize", err)
+		return metrics, nil
+	}
+
+	c.GovmomiResources.ClearCache()
+
+	catalog, err := c.buildMetricCatalog(ctx, metrics)
+	if err != nil {
+		c.catalogErr = err
+		return metrics, nil
+	}
+	return catalog, nil
+}
+
+// metricCounters returns names of vSphere perf counters needed to calculate metric with given namespace
+// and interval the counters are retrieved with
+func (c *Collector) metricCounters(ns plugin.Namespace) ([]string, int32) {
+	switch ns[nsSource].Value {
+	case "host":
+		if ns[nsHostGroup].Value == "vm" {
+			return vmMetricDepMap[ns[nsVMGroup].Value][ns[nsVMMetric].Value], defaultIntervalID
+		}
+		return hostMetricDepMap[ns[nsHostGroup].Value][ns[nsHostMetric].Value], defaultIntervalID
+	case "cluster":
+		return clusterMetricDepMap[ns[nsClusterGroup].Value][ns[nsClusterMetric].Value], historicalIntervalID
+	case "datastore":
+		return datastoreMetricDepMap[ns[nsDatastoreMetric].Value], defaultIntervalID
+	}
+	return nil, defaultIntervalID
+}
+
+// isRawCounterNs checks whether given namespace is raw perf counter namespace
+func (c *Collector) isRawCounterNs(ns plugin.Namespace) bool {
+	if ns[nsSource].Value != "host" {
+		return false
+	}
+	if ns[nsHostGroup].Value == "vm" {
+		return ns[nsVMGroup].Value == counterNs
+	}
+	return ns[nsHostGroup].Value == counterNs
+}
+
+// buildMetricCatalog filters given metrics, leaving only those which perf counters are available on vCenter,
+// and replaces generic raw perf counter metrics with metrics for each available counter
+func (c *Collector) buildMetricCatalog(ctx context.Context, metrics []plugin.Metric) ([]plugin.Metric, error) {
+	counters, err := c.GovmomiResources.RetrieveCounters(ctx)
+	if err != nil {
+		return nil, wrapError("unable to retrieve perf counters", err)
+	}
+
+	// Raw perf counter metrics are listed only for entities counter is available for,
+	// i.e. cluster services counters are not listed for hosts and VMs
+	available, err := c.GovmomiResources.FindAvailableCounters(ctx)
+	if err != nil {
+		return nil, wrapError("unable to retrieve available perf counters", err)
+	}
+
+	// Historical counters are collected only when historical interval is enabled and its level is high enough
+	historicalLevel := int32(0)
+	intervals, err := c.GovmomiResources.RetrievePerfIntervals(ctx)
+	if err != nil {
+		return nil, wrapError("unable to retrieve perf intervals", err)
+	}
+	for _, interval := range intervals {
+		if interval.SamplingPeriod == historicalIntervalID && interval.Enabled {
+			historicalLevel = interval.Level
+		}
+	}
+
+	result := []plugin.Metric{}
+	for _, m := range metrics {
+		if c.isRawCounterNs(m.Namespace) {
+			continue
+		}
+
+		available := true
+		deps, interval := c.metricCounters(m.Namespace)
+		for _, dep := range deps {
+			ctr, err := c.GovmomiResources.FindCounter(ctx, dep)
+			if err != nil || (interval == historicalIntervalID && ctr.Level > historicalLevel) {
+				available = false
+				break
+			}
+		}
+		if available {
+			result = append(result, m)
+		}
+	}
+
+	// Add raw perf counter metrics for each counter available on vCenter
+	for _, ctr := range counters {
+		group := ctr.GroupInfo.GetElementDescription()
+		counterName := ctr.NameInfo.GetElementDescription()
+		rollup := fmt.Sprint(ctr.RollupType)
+
+		unit := ""
+		if ctr.UnitInfo != nil {
+			unit = ctr.UnitInfo.GetElementDescription().Key
+		}
+		description := fmt.Sprintf("%s (stats level %d)", counterName.Summary, ctr.Level)
+
+		for _, ns := range []plugin.Namespace{c.createHostCounterNs(), c.createVMCounterNs()} {
+			entityType, nsCounterGroup := "host", nsHostCounterGroup
+			if ns[nsHostGroup].Value == "vm" {
+				entityType, nsCounterGroup = "vm", nsVMCounterGroup
+			}
+			if !available[entityType][ctr.Key] {
+				continue
+			}
+			ns[nsCounterGroup] = plugin.NamespaceElement{Value: group.Key}
+			ns[nsCounterGroup+nsCounterName] = plugin.NamespaceElement{Value: counterName.Key}
+			ns[nsCounterGroup+nsCounterRollup] = plugin.NamespaceElement{Value: rollup}
+
+			result = append(result, plugin.Metric{
+				Namespace:   ns,
+				Description: description,
+				Unit:        unit,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// staticMetricTypes returns static metric catalog, available without connection to vCenter
+func (c *Collector) staticMetricTypes() []plugin.Metric {
+	metrics := []plugin.Metric{}
+
+	// HOST - MEMORY
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("mem", "usage"),
+		Description: "Memory usage in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("mem", "free"),
+		Description: "Free memory in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("mem", "available"),
+		Description: "Available memory in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("mem", "swapUsage"),
+		Description: "Sum of memory swapped of all powered on VMs and vSphere services on the host.",
+		Unit:        "megabyte"})
+
+	// HOST - CPU
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("cpu", "idle"),
+		Description: "Total & per-core time CPU spent in an idle as a percentage during the last 20s",
+		Unit:        "percent"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("cpu", "wait"),
+		Description: "Total time CPU spent in a wait state as a percentage during the last 20s",
+		Unit:        "percent"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("cpu", "load"),
+		Description: "CPU load average over last 1 minute",
+		Unit:        "number"})
+
+	// HOST - NET
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("net", "kbrateTx"),
+		Description: "Average amount of data transmitted per second during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("net", "kbrateRx"),
+		Description: "Average amount of data received per second during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("net", "packetsTx"),
+		Description: "Number of packets transmitted during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("net", "packetsRx"),
+		Description: "Number of packets received during the last 20s",
+		Unit:        "number"})
+
+	// HOST - DISK
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "readThroughput"),
+		Description: "Average rate of reading data from the physical disk during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "writeThroughput"),
+		Description: "Average rate of writing data to the physical disk during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "readIops"),
+		Description: "Average number of read commands issued per second to the physical disk during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "writeIops"),
+		Description: "Average number of write commands issued per second to the physical disk during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "deviceLatency"),
+		Description: "Average time to complete a SCSI command issued to the physical device during the last 20s",
+		Unit:        "millisecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "kernelLatency"),
+		Description: "Average time spent by VMkernel to process each SCSI command during the last 20s",
+		Unit:        "millisecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "queueLatency"),
+		Description: "Average time each SCSI command spent in the VMkernel queue during the last 20s",
+		Unit:        "millisecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "totalLatency"),
+		Description: "Average time to process a SCSI command issued by the guest OS to the VM during the last 20s",
+		Unit:        "millisecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "commandsAborted"),
+		Description: "Number of SCSI commands aborted during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("disk", "busResets"),
+		Description: "Number of SCSI bus reset commands issued during the last 20s",
+		Unit:        "number"})
+
+	// HOST - STORAGE ADAPTER
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storageAdapter", "readThroughput"),
+		Description: "Average rate of reading data by the storage adapter during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storageAdapter", "writeThroughput"),
+		Description: "Average rate of writing data by the storage adapter during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storageAdapter", "readIops"),
+		Description: "Average number of read commands issued per second by the storage adapter during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storageAdapter", "writeIops"),
+		Description: "Average number of write commands issued per second by the storage adapter during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storageAdapter", "readLatency"),
+		Description: "Average time taken for a read by the storage adapter during the last 20s",
+		Unit:        "millisecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storageAdapter", "writeLatency"),
+		Description: "Average time taken for a write by the storage adapter during the last 20s",
+		Unit:        "millisecond"})
+
+	// HOST - STORAGE PATH
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storagePath", "readThroughput"),
+		Description: "Average rate of reading data by the storage path during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storagePath", "writeThroughput"),
+		Description: "Average rate of writing data by the storage path during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storagePath", "readIops"),
+		Description: "Average number of read commands issued per second by the storage path during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storagePath", "writeIops"),
+		Description: "Average number of write commands issued per second by the storage path during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storagePath", "readLatency"),
+		Description: "Average time taken for a read by the storage path during the last 20s",
+		Unit:        "millisecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("storagePath", "writeLatency"),
+		Description: "Average time taken for a write by the storage path during the last 20s",
+		Unit:        "millisecond"})
+
+	// HOST - POWER
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("power", "power"),
+		Description: "Current power usage of host during the last 20s",
+		Unit:        "watt"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("power", "powerCap"),
+		Description: "Maximum allowed power usage of host",
+		Unit:        "watt"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("power", "energy"),
+		Description: "Total energy used by host during the last 20s",
+		Unit:        "joule"})
+
+	// HOST - SUMMARY
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "cpuModel"),
+		Description: "CPU model of host",
+		Unit:        "string"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "cpuMhz"),
+		Description: "Speed of host CPU cores in megahertz",
+		Unit:        "megahertz"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "cpuSockets"),
+		Description: "Number of physical CPU packages on host",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "cpuCores"),
+		Description: "Number of physical CPU cores on host",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "cpuThreads"),
+		Description: "Number of physical CPU threads on host",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "cpuCapacity"),
+		Description: "Total CPU capacity of host in megahertz (speed of CPU cores multiplied by number of cores)",
+		Unit:        "megahertz"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "connectionState"),
+		Description: "Host connection state (0 - connected, 1 - disconnected, 2 - not responding)",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "powerState"),
+		Description: "Host power state (0 - powered off, 1 - powered on, 2 - standby, 3 - unknown)",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "maintenanceMode"),
+		Description: "Whether host is in maintenance mode (1 - in maintenance mode, 0 - otherwise)",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "bootTime"),
+		Description: "Host boot time as Unix timestamp",
+		Unit:        "second"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "uptime"),
+		Description: "Host uptime in seconds",
+		Unit:        "second"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("summary", "overallStatus"),
+		Description: "Host overall status (0 - gray, 1 - green, 2 - yellow, 3 - red)",
+		Unit:        "number"})
+
+	// HOST - SENSOR
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("sensor", "reading"),
+		Description: "Current reading of host hardware sensor (i.e. temperature, fan speed, voltage), unit is carried in tags",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostNs("sensor", "healthState"),
+		Description: "Health state of host hardware sensor or hardware element (0 - unknown, 1 - green, 2 - yellow, 3 - red)",
+		Unit:        "number"})
+
+	// VM - CPU
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("cpu", "usage"),
+		Description: "CPU usage as a percentage of total available CPU during the last 20s",
+		Unit:        "percent"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("cpu", "usagemhz"),
+		Description: "Total & per-vCPU CPU usage in megahertz during the last 20s",
+		Unit:        "megahertz"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("cpu", "ready"),
+		Description: "Total & per-vCPU time VM was ready to run but could not get scheduled as a percentage during the last 20s, aggregated value is normalized by number of vCPUs",
+		Unit:        "percent"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("cpu", "costop"),
+		Description: "Total & per-vCPU time VM was stopped by co-scheduling as a percentage during the last 20s, aggregated value is normalized by number of vCPUs",
+		Unit:        "percent"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("cpu", "wait"),
+		Description: "Total & per-vCPU time VM spent in a wait state during the last 20s",
+		Unit:        "millisecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("cpu", "swapwait"),
+		Description: "Total & per-vCPU time VM spent waiting for swap-in during the last 20s",
+		Unit:        "millisecond"})
+
+	// VM - MEMORY
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("mem", "active"),
+		Description: "Amount of memory actively used by VM in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("mem", "consumed"),
+		Description: "Amount of host memory consumed by VM in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("mem", "balloon"),
+		Description: "Amount of VM memory reclaimed by balloon driver in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("mem", "swapped"),
+		Description: "Amount of VM memory swapped out to disk by host in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("mem", "compressed"),
+		Description: "Amount of VM memory compressed by host in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("mem", "granted"),
+		Description: "Amount of host memory mapped to VM in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("mem", "overhead"),
+		Description: "Amount of host memory allocated for VM virtualization overhead in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("mem", "swapinRate"),
+		Description: "Rate at which VM memory is swapped in from disk during the last 20s",
+		Unit:        "megaBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("mem", "swapoutRate"),
+		Description: "Rate at which VM memory is swapped out to disk during the last 20s",
+		Unit:        "megaBytesPerSecond"})
+
+	// VM - NET
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "kbrateTx"),
+		Description: "Average amount of data transmitted per second during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "kbrateRx"),
+		Description: "Average amount of data received per second during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "packetsTx"),
+		Description: "Number of packets transmitted during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "packetsRx"),
+		Description: "Number of packets received during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "droppedTx"),
+		Description: "Number of transmitted packets dropped during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "droppedRx"),
+		Description: "Number of received packets dropped during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "broadcastTx"),
+		Description: "Number of broadcast packets transmitted during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "broadcastRx"),
+		Description: "Number of broadcast packets received during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "multicastTx"),
+		Description: "Number of multicast packets transmitted during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "multicastRx"),
+		Description: "Number of multicast packets received during the last 20s",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("net", "usage"),
+		Description: "Network utilization (combined transmit and receive rates) during the last 20s",
+		Unit:        "kiloBytesPerSecond"})
+
+	// VM - POWER
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("power", "power"),
+		Description: "Current power usage of VM during the last 20s",
+		Unit:        "watt"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("power", "energy"),
+		Description: "Total energy used by VM during the last 20s",
+		Unit:        "joule"})
+
+	// VM - SUMMARY
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("summary", "powerState"),
+		Description: "VM power state (0 - powered off, 1 - powered on, 2 - suspended)",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("summary", "connectionState"),
+		Description: "VM connection state (0 - connected, 1 - disconnected, 2 - orphaned, 3 - inaccessible, 4 - invalid)",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("summary", "overallStatus"),
+		Description: "VM overall status (0 - gray, 1 - green, 2 - yellow, 3 - red)",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("summary", "numCpu"),
+		Description: "Number of virtual CPUs configured for VM",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("summary", "memorySize"),
+		Description: "Memory size configured for VM in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("summary", "uptime"),
+		Description: "VM uptime in seconds",
+		Unit:        "second"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("summary", "toolsRunning"),
+		Description: "Whether VMware Tools are running in guest OS (1 - running, 0 - not running)",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("summary", "toolsVersionStatus"),
+		Description: "VMware Tools version status (0 - current, 1 - upgrade available, 2 - unsupported, 3 - not installed, 4 - unmanaged)",
+		Unit:        "number"})
+
+	// VM - SNAPSHOT
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("snapshot", "count"),
+		Description: "Number of VM snapshots",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("snapshot", "oldestAge"),
+		Description: "Age of the oldest VM snapshot in seconds",
+		Unit:        "second"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("snapshot", "size"),
+		Description: "Total size of VM snapshot files, including delta disks, in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("snapshot", "consolidationNeeded"),
+		Description: "Whether VM disks need consolidation (1 - consolidation needed, 0 - otherwise)",
+		Unit:        "number"})
+
+	// VM - GUEST DISK
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("guestDisk", "capacity"),
+		Description: "Capacity of guest filesystem in megabytes, reported by VMware Tools",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("guestDisk", "free"),
+		Description: "Free space of guest filesystem in megabytes, reported by VMware Tools",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("guestDisk", "usage"),
+		Description: "Used space of guest filesystem as a percentage of its capacity, reported by VMware Tools",
+		Unit:        "percent"})
+
+	// VM - VIRTUALDISK
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMNs("virtualDisk", "readIops"),
+		Description: "Read I/O operations per second",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
 		Namespace:   c.createVMNs("virtualDisk", "writeIops"),
 		Description: "Write I/O operations per second",
 		Unit:        "number"})
@@ -554,7 +2073,171 @@ func (c *Collector) GetMetricTypes(cfg plugin.Config) ([]plugin.Metric, error) {
 		Description: "Write latency",
 		Unit:        "millisecond"})
 
-	return metrics, nil
+	// CLUSTER - SUMMARY
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createClusterNs("summary", "numHosts"),
+		Description: "Total number of hosts in cluster",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createClusterNs("summary", "numEffectiveHosts"),
+		Description: "Total number of effective hosts in cluster",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createClusterNs("summary", "totalCpu"),
+		Description: "Aggregated CPU resources of all hosts in cluster in megahertz",
+		Unit:        "megahertz"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createClusterNs("summary", "totalMemory"),
+		Description: "Aggregated memory resources of all hosts in cluster in megabytes",
+		Unit:        "megabyte"})
+
+	// CLUSTER - CLUSTER SERVICES
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createClusterNs("clusterServices", "effectivecpu"),
+		Description: "Total available CPU resources of all hosts within cluster in megahertz",
+		Unit:        "megahertz"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createClusterNs("clusterServices", "effectivemem"),
+		Description: "Total amount of machine memory of all hosts in cluster that is available for VMs in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createClusterNs("clusterServices", "failover"),
+		Description: "vSphere HA number of failures that can be tolerated",
+		Unit:        "number"})
+
+	// CLUSTER - CPU & MEMORY
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createClusterNs("cpu", "usage"),
+		Description: "CPU usage of cluster as a percentage",
+		Unit:        "percent"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createClusterNs("mem", "usage"),
+		Description: "Memory usage of cluster as a percentage",
+		Unit:        "percent"})
+
+	// RESOURCE POOL
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("summary", "numVMs"),
+		Description: "Number of virtual machines in resource pool",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("cpu", "usage"),
+		Description: "CPU usage of resource pool in megahertz",
+		Unit:        "megahertz"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("cpu", "reservation"),
+		Description: "CPU guaranteed to be available for resource pool in megahertz",
+		Unit:        "megahertz"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("cpu", "limit"),
+		Description: "CPU usage limit of resource pool in megahertz, -1 if unlimited",
+		Unit:        "megahertz"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("cpu", "shares"),
+		Description: "Number of CPU shares allocated for resource pool",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("mem", "usage"),
+		Description: "Guest memory actively used by VMs in resource pool in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("mem", "consumed"),
+		Description: "Host memory consumed by VMs in resource pool in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("mem", "overhead"),
+		Description: "Memory overhead of VMs in resource pool in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("mem", "reservation"),
+		Description: "Memory guaranteed to be available for resource pool in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("mem", "limit"),
+		Description: "Memory usage limit of resource pool in megabytes, -1 if unlimited",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("mem", "shares"),
+		Description: "Number of memory shares allocated for resource pool",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createResourcePoolNs("vm", "poweredOn"),
+		Description: "Virtual machines of resource pool (VM name is metric instance), 1 if VM is powered on, 0 otherwise",
+		Unit:        "number"})
+
+	// HOST & VM - RAW PERF COUNTERS
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createHostCounterNs(),
+		Description: "Raw value of any vSphere perf counter available for host",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createVMCounterNs(),
+		Description: "Raw value of any vSphere perf counter available for virtual machine",
+		Unit:        "number"})
+
+	// ALARMS
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createAlarmNs("severity"),
+		Description: "Severity of triggered alarm (2 - yellow, 3 - red)",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createAlarmNs("acknowledged"),
+		Description: "Whether triggered alarm was acknowledged (1 - acknowledged, 0 - otherwise)",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createAlarmNs("triggeredTime"),
+		Description: "Time when alarm was triggered as Unix timestamp",
+		Unit:        "second"})
+
+	// EVENTS
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createEventNs("count"),
+		Description: "Number of vCenter events of given type created since previous collection",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createEventNs("created"),
+		Description: "Time when vCenter event was created as Unix timestamp, event message is carried in tags",
+		Unit:        "second"})
+
+	// DATASTORE
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createDsNs("capacity"),
+		Description: "Maximum capacity of datastore in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createDsNs("free"),
+		Description: "Available space of datastore in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createDsNs("provisioned"),
+		Description: "Space provisioned on datastore (used and uncommitted) in megabytes",
+		Unit:        "megabyte"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createDsNs("readIops"),
+		Description: "Average number of read commands issued per second to the datastore",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createDsNs("writeIops"),
+		Description: "Average number of write commands issued per second to the datastore",
+		Unit:        "number"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createDsNs("readThroughput"),
+		Description: "Rate of reading data from the datastore",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createDsNs("writeThroughput"),
+		Description: "Rate of writing data to the datastore",
+		Unit:        "kiloBytesPerSecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createDsNs("readLatency"),
+		Description: "Average amount of time for a read operation from the datastore",
+		Unit:        "millisecond"})
+	metrics = append(metrics, plugin.Metric{
+		Namespace:   c.createDsNs("writeLatency"),
+		Description: "Average amount of time for a write operation to the datastore",
+		Unit:        "millisecond"})
+
+	return metrics
 }
 
 // GetConfigPolicy retrieves config for the plugin
@@ -562,10 +2245,23 @@ func (c *Collector) GetConfigPolicy() (plugin.ConfigPolicy, error) {
 	policy := plugin.NewConfigPolicy()
 
 	policy.AddNewStringRule([]string{vendor, class, name}, "url", true, plugin.SetDefaultString(""))
-	policy.AddNewStringRule([]string{vendor, class, name}, "username", true, plugin.SetDefaultString(""))
-	policy.AddNewStringRule([]string{vendor, class, name}, "password", true, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "username", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "password", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "usernameEnv", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "passwordEnv", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "passwordFile", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "netrcFile", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "authMode", false, plugin.SetDefaultString(authModePassword))
+	policy.AddNewStringRule([]string{vendor, class, name}, "ssoURL", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "caFile", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "thumbprint", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "certFile", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "keyFile", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "extensionKey", false, plugin.SetDefaultString(""))
 	policy.AddNewBoolRule([]string{vendor, class, name}, "insecure", false, plugin.SetDefaultBool(false))
-	policy.AddNewStringRule([]string{vendor, class, name}, "clusterName", true, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "clusterName", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "standaloneHostName", false, plugin.SetDefaultString(""))
+	policy.AddNewStringRule([]string{vendor, class, name}, "datacenterName", false, plugin.SetDefaultString(""))
 
 	return *policy, nil
 }